@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+const maxIdentifiers = 300
+
+type CollectionRequest struct {
+	Identifiers []brew.CardIdentifier `json:"identifiers"`
+}
+
+type CollectionResponse struct {
+	Cards    []brew.Card           `json:"cards"`
+	NotFound []brew.CardIdentifier `json:"not_found"`
+}
+
+// Each identifier must use exactly one way of referring to a card
+func validateIdentifiers(ids []brew.CardIdentifier) []string {
+	errors := []string{}
+
+	if len(ids) == 0 {
+		return append(errors, "At least one identifier is required")
+	}
+	if len(ids) > maxIdentifiers {
+		return append(errors, fmt.Sprintf("At most %d identifiers can be requested at once", maxIdentifiers))
+	}
+
+	for n, i := range ids {
+		kinds := 0
+		if i.Id != "" {
+			kinds++
+		}
+		if i.MultiverseId != 0 {
+			kinds++
+		}
+		if i.Name != "" {
+			kinds++
+		}
+		if i.Set != "" || i.Number != "" {
+			if i.Set == "" || i.Number == "" {
+				errors = append(errors, fmt.Sprintf("Identifier %d must have both a set and a number", n))
+				continue
+			}
+			kinds++
+		}
+		if kinds != 1 {
+			errors = append(errors, fmt.Sprintf("Identifier %d must have one of id, multiverse_id, name or set and number", n))
+		}
+	}
+	return errors
+}
+
+func (a *API) HandleCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req CollectionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a list of identifiers"))
+		return
+	}
+	if errors := validateIdentifiers(req.Identifiers); len(errors) > 0 {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	cards, missing, err := a.c.GetCardCollection(ctx, req.Identifiers)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return
+	}
+	JSON(w, http.StatusOK, CollectionResponse{Cards: cards, NotFound: missing})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestValidateIdentifiers(t *testing.T) {
+	valid := []brew.CardIdentifier{
+		{Id: "time-vault"},
+		{MultiverseId: 1234},
+		{Name: "Jace, the Mind Sculptor"},
+		{Set: "KTK", Number: "123"},
+	}
+	if errors := validateIdentifiers(valid); len(errors) != 0 {
+		t.Errorf("Expected no errors, not %v", errors)
+	}
+
+	invalid := []brew.CardIdentifier{
+		{},
+		{Id: "time-vault", Name: "Time Vault"},
+		{Set: "KTK"},
+	}
+	if errors := validateIdentifiers(invalid); len(errors) != 3 {
+		t.Errorf("Expected three errors, not %v", errors)
+	}
+
+	if errors := validateIdentifiers(nil); len(errors) != 1 {
+		t.Errorf("Expected an error for an empty list, not %v", errors)
+	}
+
+	if errors := validateIdentifiers(make([]brew.CardIdentifier, maxIdentifiers+1)); len(errors) != 1 {
+		t.Errorf("Expected an error for too many identifiers, not %v", errors)
+	}
+}
+
+func TestIdentifierMatches(t *testing.T) {
+	card := brew.Card{
+		Id:   "jace-the-mind-sculptor",
+		Name: "Jace, the Mind Sculptor",
+		Editions: []brew.Edition{
+			{SetId: "WWK", Number: "31", MultiverseId: 195297},
+		},
+	}
+	for _, i := range []brew.CardIdentifier{
+		{Id: "jace-the-mind-sculptor"},
+		{Name: "jace, the mind sculptor"},
+		{MultiverseId: 195297},
+		{Set: "wwk", Number: "31"},
+	} {
+		if !i.Matches(&card) {
+			t.Errorf("Expected %+v to match %s", i, card.Name)
+		}
+	}
+	if (brew.CardIdentifier{Set: "wwk", Number: "32"}).Matches(&card) {
+		t.Errorf("Expected the wrong collector number not to match")
+	}
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// Decklists can be at most this many lines long, not counting blank lines,
+// comments and section headers
+const maxDeckLines = 300
+
+// A DeckLine is a single card line of a decklist
+type DeckLine struct {
+	Line     int
+	Text     string
+	Quantity int
+	Board    string
+	Name     string
+	Set      string
+	Number   string
+}
+
+// A DeckError explains why a line of a decklist couldn't be used
+type DeckError struct {
+	Line  int    `json:"line"`
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+type DeckRequest struct {
+	Decklist string `json:"decklist"`
+}
+
+type ParsedDeck struct {
+	Cards  []brew.DeckCard `json:"cards"`
+	Errors []DeckError     `json:"errors"`
+}
+
+var deckSections = map[string]string{
+	"deck":      brew.BoardMain,
+	"main":      brew.BoardMain,
+	"maindeck":  brew.BoardMain,
+	"mainboard": brew.BoardMain,
+	"side":      brew.BoardSide,
+	"sideboard": brew.BoardSide,
+	"companion": brew.BoardSide,
+	"commander": brew.BoardCommander,
+	"about":     "",
+}
+
+var (
+	// 4 Lightning Bolt, 4x Lightning Bolt
+	deckQuantity = regexp.MustCompile(`^(\d+)[xX]?\s+(.+)$`)
+	// 4 Lightning Bolt (M10) 146, as exported from Arena
+	deckArena = regexp.MustCompile(`^(.+?)\s+\(([0-9A-Za-z]+)\)(?:\s+(\S+))?$`)
+	// 4 [M10] Lightning Bolt, as used by older .dec files
+	deckBracket = regexp.MustCompile(`^\[([0-9A-Za-z]*)\]\s*(.+)$`)
+)
+
+// ParseDecklist reads the lines of a plain-text decklist. It understands
+// MTGO .dec files, Arena exports with set codes and collector numbers and
+// "4x Name" lines. Sideboard cards are marked with "SB:", follow a
+// "Sideboard" header or, in lists without headers, follow a blank line.
+func ParseDecklist(text string) ([]DeckLine, []DeckError) {
+	lines := []DeckLine{}
+	errors := []DeckError{}
+
+	board := brew.BoardMain
+	headers := false
+
+	for n, raw := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case line == "":
+			if !headers && board == brew.BoardMain && len(lines) > 0 {
+				board = brew.BoardSide
+			}
+			continue
+		case strings.HasPrefix(line, "//"), strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		if section, ok := deckSections[strings.ToLower(strings.TrimSuffix(line, ":"))]; ok {
+			board = section
+			headers = true
+			continue
+		}
+
+		// Lines in Arena's About section describe the deck, not its cards
+		if board == "" {
+			continue
+		}
+
+		l := DeckLine{Line: n + 1, Text: line, Quantity: 1, Board: board}
+		if upper := strings.ToUpper(line); strings.HasPrefix(upper, "SB:") {
+			l.Board = brew.BoardSide
+			line = strings.TrimSpace(line[3:])
+		}
+
+		if m := deckQuantity.FindStringSubmatch(line); m != nil {
+			q, err := strconv.Atoi(m[1])
+			if err != nil || q < 1 {
+				errors = append(errors, DeckError{Line: l.Line, Text: l.Text, Error: "The quantity must be at least 1"})
+				continue
+			}
+			l.Quantity = q
+			line = m[2]
+		}
+
+		if m := deckBracket.FindStringSubmatch(line); m != nil {
+			l.Set = m[1]
+			line = m[2]
+		} else if m := deckArena.FindStringSubmatch(line); m != nil {
+			line, l.Set, l.Number = m[1], m[2], m[3]
+		}
+
+		l.Name = strings.TrimSpace(line)
+		if l.Name == "" {
+			errors = append(errors, DeckError{Line: l.Line, Text: l.Text, Error: "The line is missing a card name"})
+			continue
+		}
+		lines = append(lines, l)
+	}
+
+	if len(lines) > maxDeckLines {
+		errors = append(errors, DeckError{Error: fmt.Sprintf("Decklists can have at most %d card lines", maxDeckLines)})
+		lines = lines[:maxDeckLines]
+	}
+	return lines, errors
+}
+
+// deckIdentifiers returns the identifiers needed to resolve every line.
+// Cards are found by name and their printing is picked from their editions.
+func deckIdentifiers(lines []DeckLine) []brew.CardIdentifier {
+	ids := []brew.CardIdentifier{}
+	seen := map[string]bool{}
+	for _, l := range lines {
+		if l.Name == "" || seen[strings.ToLower(l.Name)] {
+			continue
+		}
+		seen[strings.ToLower(l.Name)] = true
+		ids = append(ids, brew.CardIdentifier{Name: l.Name})
+	}
+	return ids
+}
+
+// printing returns the multiverse id of the edition a line asks for. It
+// returns zero if the line doesn't ask for one, and false if the card has no
+// such printing.
+func printing(l DeckLine, c *brew.Card) (int, bool) {
+	if l.Set == "" {
+		return 0, true
+	}
+	for _, e := range c.Editions {
+		if !strings.EqualFold(e.SetId, l.Set) {
+			continue
+		}
+		if l.Number == "" || strings.EqualFold(l.Number, e.Number) {
+			return e.MultiverseId, true
+		}
+	}
+	return 0, false
+}
+
+// MatchDecklist pairs each line with one of the given cards by name. A set
+// and collector number must be a printing of that card. Lines naming the same
+// card and printing in the same zone are combined.
+func MatchDecklist(lines []DeckLine, cards []brew.Card) ([]brew.DeckCard, []DeckError) {
+	deck := []brew.DeckCard{}
+	errors := []DeckError{}
+
+	for _, l := range lines {
+		var card *brew.Card
+		id := brew.CardIdentifier{Name: l.Name}
+		for i := range cards {
+			if id.Matches(&cards[i]) {
+				card = &cards[i]
+				break
+			}
+		}
+		if card == nil {
+			errors = append(errors, DeckError{
+				Line:  l.Line,
+				Text:  l.Text,
+				Error: fmt.Sprintf("The card '%s' is not recognized", l.Name),
+			})
+			continue
+		}
+
+		mid, ok := printing(l, card)
+		if !ok {
+			set := l.Set
+			if l.Number != "" {
+				set += " " + l.Number
+			}
+			errors = append(errors, DeckError{
+				Line:  l.Line,
+				Text:  l.Text,
+				Error: fmt.Sprintf("The printing '%s' is not a printing of %s", set, card.Name),
+			})
+			continue
+		}
+
+		merged := false
+		for i := range deck {
+			d := &deck[i]
+			if d.Card.Id == card.Id && d.Board == l.Board && d.MultiverseId == mid {
+				d.Quantity += l.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			deck = append(deck, brew.DeckCard{
+				Quantity:     l.Quantity,
+				Board:        l.Board,
+				MultiverseId: mid,
+				Card:         *card,
+			})
+		}
+	}
+	return deck, errors
+}
+
+// resolveDecklist parses a decklist and finds every card in it
+func (a *API) resolveDecklist(ctx context.Context, text string) ([]brew.DeckCard, []DeckError, error) {
+	lines, errors := ParseDecklist(text)
+	if len(lines) == 0 {
+		return []brew.DeckCard{}, errors, nil
+	}
+	cards, _, err := a.c.GetCardCollection(ctx, deckIdentifiers(lines))
+	if err != nil {
+		return []brew.DeckCard{}, errors, err
+	}
+	deck, missing := MatchDecklist(lines, cards)
+	return deck, append(errors, missing...), nil
+}
+
+func readDeckRequest(w http.ResponseWriter, r *http.Request, req interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(req)
+}
+
+func (a *API) HandleParseDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req DeckRequest
+	if err := readDeckRequest(w, r, &req); err != nil || strings.TrimSpace(req.Decklist) == "" {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a decklist"))
+		return
+	}
+	deck, errors, err := a.resolveDecklist(ctx, req.Decklist)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return
+	}
+	JSON(w, http.StatusOK, ParsedDeck{Cards: deck, Errors: errors})
+}
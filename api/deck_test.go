@@ -0,0 +1,130 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestParseDecklist(t *testing.T) {
+	text := `// Burn
+4 Lightning Bolt
+4x Goblin Guide
+1 Mountain (M10) 242
+2 [M10] Shock
+
+SB: 2 Pyroblast
+3 Smash to Smithereens`
+
+	lines, errors := ParseDecklist(text)
+	if len(errors) > 0 {
+		t.Fatalf("Unexpected errors %+v", errors)
+	}
+	expected := []DeckLine{
+		{Line: 2, Text: "4 Lightning Bolt", Quantity: 4, Board: brew.BoardMain, Name: "Lightning Bolt"},
+		{Line: 3, Text: "4x Goblin Guide", Quantity: 4, Board: brew.BoardMain, Name: "Goblin Guide"},
+		{Line: 4, Text: "1 Mountain (M10) 242", Quantity: 1, Board: brew.BoardMain, Name: "Mountain", Set: "M10", Number: "242"},
+		{Line: 5, Text: "2 [M10] Shock", Quantity: 2, Board: brew.BoardMain, Name: "Shock", Set: "M10"},
+		{Line: 7, Text: "SB: 2 Pyroblast", Quantity: 2, Board: brew.BoardSide, Name: "Pyroblast"},
+		{Line: 8, Text: "3 Smash to Smithereens", Quantity: 3, Board: brew.BoardSide, Name: "Smash to Smithereens"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %+v not %+v", expected, lines)
+	}
+}
+
+func TestParseDecklistSections(t *testing.T) {
+	text := `About
+Name Mono Green
+
+Commander
+1 Omnath, Locus of Mana (WWK) 105
+
+Deck
+1 Llanowar Elves (M19) 314
+
+Sideboard
+1 Naturalize
+0 Giant Growth`
+
+	lines, errors := ParseDecklist(text)
+	if len(errors) != 1 || errors[0].Line != 12 {
+		t.Errorf("Expected an error on line 12, not %+v", errors)
+	}
+	boards := []string{}
+	for _, l := range lines {
+		boards = append(boards, l.Board)
+	}
+	expected := []string{brew.BoardCommander, brew.BoardMain, brew.BoardSide}
+	if !reflect.DeepEqual(boards, expected) {
+		t.Errorf("Expected boards %v not %v", expected, boards)
+	}
+	if lines[0].Name != "Omnath, Locus of Mana" || lines[0].Set != "WWK" || lines[0].Number != "105" {
+		t.Errorf("Expected an Arena line, not %+v", lines[0])
+	}
+}
+
+func TestMatchDecklist(t *testing.T) {
+	cards := []brew.Card{
+		{Id: "lightning-bolt", Name: "Lightning Bolt", Editions: []brew.Edition{
+			{SetId: "M10", Number: "146", MultiverseId: 191089},
+			{SetId: "LEA", Number: "161", MultiverseId: 209},
+		}},
+		{Id: "mountain", Name: "Mountain", Editions: []brew.Edition{
+			{SetId: "M10", Number: "242", MultiverseId: 191401},
+		}},
+	}
+	lines, _ := ParseDecklist("2 Lightning Bolt\n2 lightning bolt\n1 Lightning Bolt (LEA) 161\n20 Mountain (m10) 242\n4 Lightning Helix")
+
+	deck, errors := MatchDecklist(lines, cards)
+	expected := []brew.DeckCard{
+		{Quantity: 4, Board: brew.BoardMain, Card: cards[0]},
+		{Quantity: 1, Board: brew.BoardMain, MultiverseId: 209, Card: cards[0]},
+		{Quantity: 20, Board: brew.BoardMain, MultiverseId: 191401, Card: cards[1]},
+	}
+	if !reflect.DeepEqual(deck, expected) {
+		t.Errorf("Expected %+v not %+v", expected, deck)
+	}
+	if len(errors) != 1 || errors[0].Line != 5 {
+		t.Errorf("Expected an error on line 5, not %+v", errors)
+	}
+}
+
+func TestMatchDecklistPrintings(t *testing.T) {
+	cards := []brew.Card{
+		{Id: "lightning-bolt", Name: "Lightning Bolt", Editions: []brew.Edition{
+			{SetId: "M10", Number: "146", MultiverseId: 191089},
+		}},
+		{Id: "mountain", Name: "Mountain", Editions: []brew.Edition{
+			{SetId: "M10", Number: "242", MultiverseId: 191401},
+		}},
+	}
+
+	// M10 242 is Mountain, which doesn't agree with the name on the line, and
+	// there is no XXX set at all
+	lines, _ := ParseDecklist("4 Lightning Bolt (M10) 242\n4 Lightning Bolt (XXX) 1\n2 [LEA] Lightning Bolt")
+	deck, errors := MatchDecklist(lines, cards)
+	if len(deck) != 0 {
+		t.Errorf("Expected no cards, not %+v", deck)
+	}
+	expected := []DeckError{
+		{Line: 1, Text: "4 Lightning Bolt (M10) 242", Error: "The printing 'M10 242' is not a printing of Lightning Bolt"},
+		{Line: 2, Text: "4 Lightning Bolt (XXX) 1", Error: "The printing 'XXX 1' is not a printing of Lightning Bolt"},
+		{Line: 3, Text: "2 [LEA] Lightning Bolt", Error: "The printing 'LEA' is not a printing of Lightning Bolt"},
+	}
+	if !reflect.DeepEqual(errors, expected) {
+		t.Errorf("Expected %+v not %+v", expected, errors)
+	}
+}
+
+func TestDeckIdentifiers(t *testing.T) {
+	lines, _ := ParseDecklist("4 Lightning Bolt\n1 Lightning Bolt (LEA) 161\n2 [M10] Shock\n1 lightning bolt")
+	expected := []brew.CardIdentifier{
+		{Name: "Lightning Bolt"},
+		{Name: "Shock"},
+	}
+	if ids := deckIdentifiers(lines); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %+v not %+v", expected, ids)
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"goji.io/pat"
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// A SaveDeckRequest creates or replaces a deck. Cards are given either as
+// references or as a plain-text decklist.
+type SaveDeckRequest struct {
+	Name     string             `json:"name"`
+	Format   string             `json:"format"`
+	Cards    []brew.DeckCardRef `json:"cards"`
+	Decklist string             `json:"decklist"`
+}
+
+var validBoards = map[string]bool{
+	brew.BoardMain:      true,
+	brew.BoardSide:      true,
+	brew.BoardCommander: true,
+}
+
+func validateDeckRequest(req SaveDeckRequest) []string {
+	errors := []string{}
+
+	if strings.TrimSpace(req.Name) == "" {
+		errors = append(errors, "A deck must have a name")
+	}
+	if len(req.Name) > 200 {
+		errors = append(errors, "A deck name can be at most 200 characters")
+	}
+	if req.Format != "" && !validFormats[req.Format] {
+		errors = append(errors, fmt.Sprintf("The format '%s' is not recognized", req.Format))
+	}
+	if len(req.Cards) > 0 && req.Decklist != "" {
+		errors = append(errors, "A deck can have either cards or a decklist, not both")
+	}
+	if len(req.Cards) > maxDeckLines {
+		errors = append(errors, fmt.Sprintf("A deck can have at most %d cards", maxDeckLines))
+	}
+
+	for n, c := range req.Cards {
+		if c.Quantity < 1 {
+			errors = append(errors, fmt.Sprintf("Card %d must have a quantity of at least 1", n))
+		}
+		if !validBoards[c.Board] {
+			errors = append(errors, fmt.Sprintf("The board '%s' is not recognized", c.Board))
+		}
+		if c.Id == "" && c.MultiverseId == 0 {
+			errors = append(errors, fmt.Sprintf("Card %d must have an id or multiverse_id", n))
+		}
+	}
+	return errors
+}
+
+// matchDeckCards pairs each reference with its card. A multiverse id must be
+// one of the card's printings, even when an id is also given.
+func matchDeckCards(refs []brew.DeckCardRef, ids []brew.CardIdentifier, cards []brew.Card) ([]brew.DeckCard, []string) {
+	deck := []brew.DeckCard{}
+	errors := []string{}
+	for n, ref := range refs {
+		var card *brew.Card
+		for i := range cards {
+			if ids[n].Matches(&cards[i]) {
+				card = &cards[i]
+				break
+			}
+		}
+		if card == nil {
+			errors = append(errors, fmt.Sprintf("Card %d is not recognized", n))
+			continue
+		}
+		if ref.MultiverseId != 0 && !hasPrinting(card, ref.MultiverseId) {
+			errors = append(errors, fmt.Sprintf("Card %d has a multiverse_id that isn't a printing of %s", n, card.Name))
+			continue
+		}
+		deck = append(deck, brew.DeckCard{
+			Quantity:     ref.Quantity,
+			Board:        ref.Board,
+			MultiverseId: ref.MultiverseId,
+			Card:         *card,
+		})
+	}
+	return deck, errors
+}
+
+func hasPrinting(c *brew.Card, multiverseId int) bool {
+	for i := range c.Editions {
+		if c.Editions[i].HasMultiverseId(multiverseId) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingCards explains each saved card that couldn't be found, such as a
+// card that has since been renamed
+func missingCards(refs []brew.DeckCardRef) []string {
+	errors := []string{}
+	for _, ref := range refs {
+		errors = append(errors, fmt.Sprintf("The card '%s' in the %s no longer exists", ref.Id, ref.Board))
+	}
+	return errors
+}
+
+// resolveDeckCards finds the card each reference points to
+func (a *API) resolveDeckCards(ctx context.Context, refs []brew.DeckCardRef) ([]brew.DeckCard, []string, error) {
+	if len(refs) == 0 {
+		return []brew.DeckCard{}, []string{}, nil
+	}
+
+	ids := []brew.CardIdentifier{}
+	for _, ref := range refs {
+		ids = append(ids, brew.CardIdentifier{Id: ref.Id, MultiverseId: ref.MultiverseId})
+	}
+	cards, _, err := a.c.GetCardCollection(ctx, ids)
+	if err != nil {
+		return []brew.DeckCard{}, []string{}, err
+	}
+	deck, errors := matchDeckCards(refs, ids, cards)
+	return deck, errors, nil
+}
+
+// readDeck reads a deck from the request body, writing an error response if
+// the deck isn't valid
+func (a *API) readDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) (brew.Deck, bool) {
+	var req SaveDeckRequest
+	if err := readDeckRequest(w, r, &req); err != nil {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a deck"))
+		return brew.Deck{}, false
+	}
+	req.Format = strings.ToLower(req.Format)
+	if errors := validateDeckRequest(req); len(errors) > 0 {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return brew.Deck{}, false
+	}
+
+	deck := brew.Deck{Name: strings.TrimSpace(req.Name), Format: req.Format}
+	errors := []string{}
+	var err error
+	if req.Decklist != "" {
+		var lineErrors []DeckError
+		deck.Cards, lineErrors, err = a.resolveDecklist(ctx, req.Decklist)
+		for _, e := range lineErrors {
+			errors = append(errors, fmt.Sprintf("Line %d: %s", e.Line, e.Error))
+		}
+	} else {
+		deck.Cards, errors, err = a.resolveDeckCards(ctx, req.Cards)
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return deck, false
+	}
+	if len(errors) > 0 {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return deck, false
+	}
+	return deck, true
+}
+
+func (a *API) HandleCreateDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	deck, ok := a.readDeck(ctx, w, r)
+	if !ok {
+		return
+	}
+	deck, err := a.d.CreateDeck(ctx, deck)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error saving deck"))
+		return
+	}
+	w.Header().Set("Location", deck.Href)
+	JSON(w, http.StatusCreated, deck)
+}
+
+func (a *API) HandleDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	deck, err := a.d.GetDeck(ctx, pat.Param(ctx, "id"))
+	if err == brew.ErrDeckNotFound {
+		JSON(w, http.StatusNotFound, Errors("Deck not found"))
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching deck"))
+		return
+	}
+	JSON(w, http.StatusOK, deck)
+}
+
+func (a *API) HandleUpdateDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	deck, ok := a.readDeck(ctx, w, r)
+	if !ok {
+		return
+	}
+	deck.Id = pat.Param(ctx, "id")
+	deck, err := a.d.UpdateDeck(ctx, deck)
+	if err == brew.ErrDeckNotFound {
+		JSON(w, http.StatusNotFound, Errors("Deck not found"))
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error saving deck"))
+		return
+	}
+	JSON(w, http.StatusOK, deck)
+}
+
+func (a *API) HandleDeleteDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	err := a.d.DeleteDeck(ctx, pat.Param(ctx, "id"))
+	if err == brew.ErrDeckNotFound {
+		JSON(w, http.StatusNotFound, Errors("Deck not found"))
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error deleting deck"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestValidateDeckRequest(t *testing.T) {
+	valid := SaveDeckRequest{
+		Name:   "Burn",
+		Format: "modern",
+		Cards: []brew.DeckCardRef{
+			{Quantity: 4, Board: "mainboard", Id: "lightning-bolt"},
+			{Quantity: 2, Board: "sideboard", MultiverseId: 191089},
+		},
+	}
+	if errors := validateDeckRequest(valid); len(errors) > 0 {
+		t.Errorf("Expected no errors, not %v", errors)
+	}
+
+	for _, req := range []SaveDeckRequest{
+		{Name: ""},
+		{Name: strings.Repeat("a", 201)},
+		{Name: "Burn", Format: "pauper"},
+		{Name: "Burn", Decklist: "4 Lightning Bolt", Cards: valid.Cards},
+		{Name: "Burn", Cards: []brew.DeckCardRef{{Quantity: 0, Board: "mainboard", Id: "lightning-bolt"}}},
+		{Name: "Burn", Cards: []brew.DeckCardRef{{Quantity: 1, Board: "maybeboard", Id: "lightning-bolt"}}},
+		{Name: "Burn", Cards: []brew.DeckCardRef{{Quantity: 1, Board: "mainboard"}}},
+	} {
+		if errors := validateDeckRequest(req); len(errors) != 1 {
+			t.Errorf("Expected one error for %+v, not %v", req, errors)
+		}
+	}
+}
+
+func TestMatchDeckCards(t *testing.T) {
+	bolt := brew.Card{Id: "lightning-bolt", Name: "Lightning Bolt",
+		Editions: []brew.Edition{{MultiverseId: 191089}, {MultiverseId: 209}}}
+	helix := brew.Card{Id: "lightning-helix", Name: "Lightning Helix",
+		Editions: []brew.Edition{{MultiverseId: 87908}}}
+	cards := []brew.Card{bolt, helix}
+
+	refs := []brew.DeckCardRef{
+		{Quantity: 4, Board: "mainboard", Id: "lightning-bolt", MultiverseId: 209},
+		{Quantity: 2, Board: "mainboard", MultiverseId: 87908},
+		{Quantity: 1, Board: "sideboard", Id: "lightning-bolt", MultiverseId: 87908},
+		{Quantity: 1, Board: "sideboard", Id: "shock"},
+	}
+	ids := []brew.CardIdentifier{}
+	for _, ref := range refs {
+		ids = append(ids, brew.CardIdentifier{Id: ref.Id, MultiverseId: ref.MultiverseId})
+	}
+
+	deck, errors := matchDeckCards(refs, ids, cards)
+	expected := []brew.DeckCard{
+		{Quantity: 4, Board: "mainboard", MultiverseId: 209, Card: bolt},
+		{Quantity: 2, Board: "mainboard", MultiverseId: 87908, Card: helix},
+	}
+	if !reflect.DeepEqual(deck, expected) {
+		t.Errorf("Expected %+v not %+v", expected, deck)
+	}
+	expectedErrors := []string{
+		"Card 2 has a multiverse_id that isn't a printing of Lightning Bolt",
+		"Card 3 is not recognized",
+	}
+	if !reflect.DeepEqual(errors, expectedErrors) {
+		t.Errorf("Expected %v not %v", expectedErrors, errors)
+	}
+}
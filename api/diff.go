@@ -0,0 +1,268 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// A DiffSide is one of the decks being compared, given either as a decklist
+// or as the id of a saved deck
+type DiffSide struct {
+	Decklist string `json:"decklist"`
+	DeckId   string `json:"deck_id"`
+}
+
+type DiffRequest struct {
+	From DiffSide `json:"from"`
+	To   DiffSide `json:"to"`
+}
+
+// A CardChange is a card whose number of copies in a zone changed. From is
+// zero for added cards and To is zero for removed cards.
+type CardChange struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+type Printing struct {
+	MultiverseId int    `json:"multiverse_id"`
+	SetId        string `json:"set_id"`
+	Number       string `json:"number,omitempty"`
+}
+
+// A PrintingChange is a number of copies of a card swapped from one printing
+// to another
+type PrintingChange struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Quantity int      `json:"quantity"`
+	From     Printing `json:"from"`
+	To       Printing `json:"to"`
+}
+
+type ZoneDiff struct {
+	Added     []CardChange     `json:"added"`
+	Removed   []CardChange     `json:"removed"`
+	Changed   []CardChange     `json:"changed"`
+	Printings []PrintingChange `json:"printings"`
+}
+
+type DiffErrors struct {
+	From []DeckError `json:"from"`
+	To   []DeckError `json:"to"`
+}
+
+type DiffResult struct {
+	Zones  map[string]ZoneDiff `json:"zones"`
+	Errors DiffErrors          `json:"errors"`
+}
+
+// zoneCard is every copy of a card in one zone, counted by the printing the
+// deck chose. Copies without a chosen printing are counted under zero.
+type zoneCard struct {
+	card      *brew.Card
+	total     int
+	printings map[int]int
+	editions  map[int]*brew.Edition
+}
+
+func (z *zoneCard) ids() []int {
+	ids := []int{}
+	for id := range z.printings {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func zoneCards(deck []brew.DeckCard, board string) map[string]*zoneCard {
+	zone := map[string]*zoneCard{}
+	for i := range deck {
+		d := &deck[i]
+		if d.Board != board {
+			continue
+		}
+		z, ok := zone[d.Card.Id]
+		if !ok {
+			z = &zoneCard{card: &d.Card, printings: map[int]int{}, editions: map[int]*brew.Edition{}}
+			zone[d.Card.Id] = z
+		}
+		id := d.MultiverseId
+		if e := d.Edition(); id != 0 && e != nil && e.HasMultiverseId(id) {
+			z.editions[id] = e
+		}
+		z.total += d.Quantity
+		z.printings[id] += d.Quantity
+	}
+	return zone
+}
+
+func zonePrinting(z *zoneCard, id int) Printing {
+	p := Printing{MultiverseId: id}
+	if e := z.editions[id]; e != nil {
+		p.SetId, p.Number = e.SetId, e.Number
+	}
+	return p
+}
+
+// printingChanges pairs up the copies that left one chosen printing with the
+// copies that arrived in another. Copies without a chosen printing aren't
+// paired, since there's no printing to compare. Copies that can't be paired
+// are added or removed cards, which are counted separately.
+func printingChanges(from, to *zoneCard) []PrintingChange {
+	changes := []PrintingChange{}
+	removed := map[int]int{}
+	added := map[int]int{}
+	for id, n := range from.printings {
+		if d := n - to.printings[id]; d > 0 {
+			removed[id] = d
+		}
+	}
+	for id, n := range to.printings {
+		if d := n - from.printings[id]; d > 0 {
+			added[id] = d
+		}
+	}
+	for _, fid := range from.ids() {
+		for _, tid := range to.ids() {
+			if fid == 0 || tid == 0 {
+				continue
+			}
+			n := removed[fid]
+			if added[tid] < n {
+				n = added[tid]
+			}
+			if n == 0 {
+				continue
+			}
+			removed[fid] -= n
+			added[tid] -= n
+			changes = append(changes, PrintingChange{
+				Id:       to.card.Id,
+				Name:     to.card.Name,
+				Quantity: n,
+				From:     zonePrinting(from, fid),
+				To:       zonePrinting(to, tid),
+			})
+		}
+	}
+	return changes
+}
+
+// DiffDecks compares two decks zone by zone. Cards are listed by name.
+func DiffDecks(from, to []brew.DeckCard) map[string]ZoneDiff {
+	zones := map[string]ZoneDiff{}
+	for _, board := range []string{brew.BoardMain, brew.BoardSide, brew.BoardCommander} {
+		before, after := zoneCards(from, board), zoneCards(to, board)
+		diff := ZoneDiff{
+			Added:     []CardChange{},
+			Removed:   []CardChange{},
+			Changed:   []CardChange{},
+			Printings: []PrintingChange{},
+		}
+		for id, b := range before {
+			if _, ok := after[id]; !ok {
+				diff.Removed = append(diff.Removed, CardChange{Id: id, Name: b.card.Name, From: b.total})
+			}
+		}
+		for id, a := range after {
+			b, ok := before[id]
+			if !ok {
+				diff.Added = append(diff.Added, CardChange{Id: id, Name: a.card.Name, To: a.total})
+				continue
+			}
+			if a.total != b.total {
+				diff.Changed = append(diff.Changed, CardChange{Id: id, Name: a.card.Name, From: b.total, To: a.total})
+			}
+			diff.Printings = append(diff.Printings, printingChanges(b, a)...)
+		}
+
+		for _, changes := range [][]CardChange{diff.Added, diff.Removed, diff.Changed} {
+			sort.Sort(byChangeName(changes))
+		}
+		sort.Sort(byPrintingName(diff.Printings))
+		zones[board] = diff
+	}
+	return zones
+}
+
+type byChangeName []CardChange
+
+func (a byChangeName) Len() int           { return len(a) }
+func (a byChangeName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byChangeName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+type byPrintingName []PrintingChange
+
+func (a byPrintingName) Len() int      { return len(a) }
+func (a byPrintingName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byPrintingName) Less(i, j int) bool {
+	if a[i].Name != a[j].Name {
+		return a[i].Name < a[j].Name
+	}
+	if a[i].From.MultiverseId != a[j].From.MultiverseId {
+		return a[i].From.MultiverseId < a[j].From.MultiverseId
+	}
+	return a[i].To.MultiverseId < a[j].To.MultiverseId
+}
+
+// resolveDiffSide loads a saved deck or resolves a decklist, writing an error
+// response if neither can be used. Saved cards that no longer exist can't be
+// compared, so they're returned as errors without a line.
+func (a *API) resolveDiffSide(ctx context.Context, w http.ResponseWriter, side DiffSide, name string) ([]brew.DeckCard, []DeckError, bool) {
+	errors := []DeckError{}
+	hasList := strings.TrimSpace(side.Decklist) != ""
+	if hasList == (side.DeckId != "") {
+		JSON(w, http.StatusBadRequest, Errors(fmt.Sprintf("The %s deck must have either a decklist or a deck_id", name)))
+		return nil, errors, false
+	}
+	if hasList {
+		deck, errors, err := a.resolveDecklist(ctx, side.Decklist)
+		if err != nil {
+			JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+			return nil, errors, false
+		}
+		return deck, errors, true
+	}
+	deck, err := a.d.GetDeck(ctx, side.DeckId)
+	if err == brew.ErrDeckNotFound {
+		JSON(w, http.StatusNotFound, Errors(fmt.Sprintf("The %s deck was not found", name)))
+		return nil, errors, false
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching deck"))
+		return nil, errors, false
+	}
+	for _, e := range missingCards(deck.Missing) {
+		errors = append(errors, DeckError{Error: e})
+	}
+	return deck.Cards, errors, true
+}
+
+func (a *API) HandleDiffDecks(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req DiffRequest
+	if err := readDeckRequest(w, r, &req); err != nil {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a from and to deck"))
+		return
+	}
+	from, fromErrors, ok := a.resolveDiffSide(ctx, w, req.From, "from")
+	if !ok {
+		return
+	}
+	to, toErrors, ok := a.resolveDiffSide(ctx, w, req.To, "to")
+	if !ok {
+		return
+	}
+	JSON(w, http.StatusOK, DiffResult{
+		Zones:  DiffDecks(from, to),
+		Errors: DiffErrors{From: fromErrors, To: toErrors},
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestDiffDecks(t *testing.T) {
+	bolt := brew.Card{Id: "lightning-bolt", Name: "Lightning Bolt", Editions: []brew.Edition{
+		{SetId: "M10", Number: "146", MultiverseId: 191089},
+		{SetId: "LEA", Number: "161", MultiverseId: 209},
+	}}
+	helix := brew.Card{Id: "lightning-helix", Name: "Lightning Helix",
+		Editions: []brew.Edition{{SetId: "RAV", MultiverseId: 87908}}}
+	guide := brew.Card{Id: "goblin-guide", Name: "Goblin Guide",
+		Editions: []brew.Edition{{SetId: "ZEN", MultiverseId: 170987}}}
+	smash := brew.Card{Id: "smash-to-smithereens", Name: "Smash to Smithereens",
+		Editions: []brew.Edition{{SetId: "ALA", MultiverseId: 174822}}}
+
+	from := []brew.DeckCard{
+		{Quantity: 4, Board: brew.BoardMain, MultiverseId: 191089, Card: bolt},
+		{Quantity: 2, Board: brew.BoardMain, Card: helix},
+		{Quantity: 4, Board: brew.BoardMain, Card: guide},
+		{Quantity: 2, Board: brew.BoardSide, Card: smash},
+	}
+	to := []brew.DeckCard{
+		{Quantity: 1, Board: brew.BoardMain, MultiverseId: 191089, Card: bolt},
+		{Quantity: 3, Board: brew.BoardMain, MultiverseId: 209, Card: bolt},
+		{Quantity: 3, Board: brew.BoardMain, Card: guide},
+		{Quantity: 3, Board: brew.BoardSide, Card: smash},
+		{Quantity: 1, Board: brew.BoardSide, Card: helix},
+	}
+
+	zones := DiffDecks(from, to)
+
+	main := ZoneDiff{
+		Added:   []CardChange{},
+		Removed: []CardChange{{Id: "lightning-helix", Name: "Lightning Helix", From: 2}},
+		Changed: []CardChange{{Id: "goblin-guide", Name: "Goblin Guide", From: 4, To: 3}},
+		Printings: []PrintingChange{{
+			Id:       "lightning-bolt",
+			Name:     "Lightning Bolt",
+			Quantity: 3,
+			From:     Printing{MultiverseId: 191089, SetId: "M10", Number: "146"},
+			To:       Printing{MultiverseId: 209, SetId: "LEA", Number: "161"},
+		}},
+	}
+	if !reflect.DeepEqual(zones[brew.BoardMain], main) {
+		t.Errorf("Expected %+v not %+v", main, zones[brew.BoardMain])
+	}
+
+	side := ZoneDiff{
+		Added:     []CardChange{{Id: "lightning-helix", Name: "Lightning Helix", To: 1}},
+		Removed:   []CardChange{},
+		Changed:   []CardChange{{Id: "smash-to-smithereens", Name: "Smash to Smithereens", From: 2, To: 3}},
+		Printings: []PrintingChange{},
+	}
+	if !reflect.DeepEqual(zones[brew.BoardSide], side) {
+		t.Errorf("Expected %+v not %+v", side, zones[brew.BoardSide])
+	}
+
+	commander := zones[brew.BoardCommander]
+	if len(commander.Added)+len(commander.Removed)+len(commander.Changed)+len(commander.Printings) != 0 {
+		t.Errorf("Expected no commander changes, not %+v", commander)
+	}
+}
+
+func TestDiffDecksUnchosenPrinting(t *testing.T) {
+	bolt := brew.Card{Id: "lightning-bolt", Name: "Lightning Bolt", Editions: []brew.Edition{
+		{SetId: "M10", MultiverseId: 191089},
+		{SetId: "LEA", MultiverseId: 209},
+	}}
+
+	// Picking a printing for some copies isn't a change from any printing
+	from := []brew.DeckCard{{Quantity: 4, Board: brew.BoardMain, Card: bolt}}
+	to := []brew.DeckCard{
+		{Quantity: 1, Board: brew.BoardMain, Card: bolt},
+		{Quantity: 3, Board: brew.BoardMain, MultiverseId: 209, Card: bolt},
+	}
+	diff := DiffDecks(from, to)[brew.BoardMain]
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed)+len(diff.Printings) != 0 {
+		t.Errorf("Expected no changes, not %+v", diff)
+	}
+
+	to = []brew.DeckCard{{Quantity: 2, Board: brew.BoardMain, MultiverseId: 209, Card: bolt}}
+	diff = DiffDecks(from, to)[brew.BoardMain]
+	changed := []CardChange{{Id: "lightning-bolt", Name: "Lightning Bolt", From: 4, To: 2}}
+	if !reflect.DeepEqual(diff.Changed, changed) {
+		t.Errorf("Expected %+v not %+v", changed, diff.Changed)
+	}
+	if len(diff.Printings) != 0 {
+		t.Errorf("Expected no printing changes, not %+v", diff.Printings)
+	}
+}
+
+func TestHandleDiffDecksMissing(t *testing.T) {
+	bolt := brew.Card{Id: "lightning-bolt", Name: "Lightning Bolt"}
+	api := &API{d: savedDecks{decks: map[string]brew.Deck{
+		"before": {Cards: []brew.DeckCard{{Quantity: 4, Board: brew.BoardMain, Card: bolt}}},
+		"after": {
+			Cards:   []brew.DeckCard{{Quantity: 4, Board: brew.BoardMain, Card: bolt}},
+			Missing: []brew.DeckCardRef{{Quantity: 2, Board: brew.BoardMain, Id: "renamed-card"}},
+		},
+	}}}
+
+	body := `{"from": {"deck_id": "before"}, "to": {"deck_id": "after"}}`
+	r, _ := http.NewRequest("POST", "/mtg/decks/diff", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	api.HandleDiffDecks(context.Background(), w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 not %d: %s", w.Code, w.Body.String())
+	}
+	var result DiffResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	expected := DiffErrors{
+		From: []DeckError{},
+		To:   []DeckError{{Error: "The card 'renamed-card' in the mainboard no longer exists"}},
+	}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("Expected %+v not %+v", expected, result.Errors)
+	}
+}
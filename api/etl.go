@@ -3,6 +3,7 @@ package api
 import (
 	"archive/zip"
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -63,22 +65,84 @@ func TransformEdition(s MTGSet, c MTGCard) brew.Edition {
 	}
 }
 
-// FIXME: Add released dates
 func TransformSet(s MTGSet) brew.Set {
 	return brew.Set{
-		Name:   s.Name,
-		Id:     s.Code,
-		Border: s.Border,
-		Type:   s.Type,
+		Name:     s.Name,
+		Id:       s.Code,
+		Border:   s.Border,
+		Type:     s.Type,
+		Released: s.Released,
+		Block:    s.Block,
+		Size:     len(s.Cards),
 	}
 }
 
+var manaSymbol = regexp.MustCompile(`\{([^}]+)\}`)
+var reminderText = regexp.MustCompile(`\([^)]*\)`)
+
+var symbolColors = map[string]string{
+	"W": "white",
+	"U": "blue",
+	"B": "black",
+	"R": "red",
+	"G": "green",
+}
+
+// symbolPips returns the color of every colored part of the mana symbols in
+// the text. Hybrid, phyrexian and half mana symbols have more than one part,
+// such as {W/U}, {2/B}, {G/P} or {HR}, and hybrid symbols add each color.
+func symbolPips(text string) []string {
+	pips := []string{}
+	for _, match := range manaSymbol.FindAllStringSubmatch(text, -1) {
+		for _, part := range strings.Split(match[1], "/") {
+			if len(part) == 2 && part[0] == 'H' {
+				part = part[1:]
+			}
+			if color, ok := symbolColors[part]; ok {
+				pips = append(pips, color)
+			}
+		}
+	}
+	return pips
+}
+
+// ColorIdentity returns the colors of every mana symbol in a card's mana
+// cost and rules text, along with the card's own colors. Mana symbols in
+// reminder text are ignored.
+func ColorIdentity(c MTGCard) []string {
+	seen := map[string]bool{}
+	for _, color := range c.Colors {
+		seen[strings.ToLower(color)] = true
+	}
+
+	text := c.ManaCost + " " + reminderText.ReplaceAllString(c.Text, "")
+	for _, color := range symbolPips(text) {
+		seen[color] = true
+	}
+
+	identity := []string{}
+	for color := range seen {
+		identity = append(identity, color)
+	}
+	sort.Strings(identity)
+	return identity
+}
+
+func TransformRulings(rulings []MTGRuling) []brew.Ruling {
+	r := []brew.Ruling{}
+	for _, ruling := range rulings {
+		r = append(r, brew.Ruling{Date: ruling.Date, Text: ruling.Text})
+	}
+	return r
+}
+
 func TransformCard(c MTGCard) brew.Card {
 	return brew.Card{
 		Name:          c.Name,
 		Id:            Slug(c.Name),
 		Text:          c.Text,
 		Colors:        ToSortedLower(c.Colors),
+		ColorIdentity: ColorIdentity(c),
 		Types:         ToSortedLower(c.Types),
 		Supertypes:    ToSortedLower(c.Supertypes),
 		Subtypes:      ToSortedLower(c.Subtypes),
@@ -88,39 +152,202 @@ func TransformCard(c MTGCard) brew.Card {
 		ManaCost:      c.ManaCost,
 		FormatMap:     TransformLegalities(c.Legalities),
 		ConvertedCost: int(c.ConvertedCost),
+		Rulings:       TransformRulings(c.Rulings),
+	}
+}
+
+// FaceNames returns the names of the faces that make up a card, front face
+// first. Meld cards are printed as two cards that share a back face, which
+// isn't a card of its own, so it returns nil for the back face.
+func FaceNames(c MTGCard) []string {
+	switch c.Layout {
+	case "split", "flip", "double-faced", "aftermath":
+		if len(c.Names) > 1 {
+			return c.Names
+		}
+	case "meld":
+		if len(c.Names) > 1 {
+			back := c.Names[len(c.Names)-1]
+			if c.Name == back {
+				return nil
+			}
+			return []string{c.Name, back}
+		}
+	}
+	return []string{c.Name}
+}
+
+func union(lists ...[]string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, list := range lists {
+		for _, v := range list {
+			if !seen[v] {
+				values = append(values, v)
+				seen[v] = true
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func TransformFace(c MTGCard) brew.Face {
+	return brew.Face{
+		Name:      c.Name,
+		ManaCost:  c.ManaCost,
+		Type:      c.Type,
+		Colors:    ToSortedLower(c.Colors),
+		Text:      c.Text,
+		Power:     c.Power,
+		Toughness: c.Toughness,
+		Loyalty:   c.Loyalty,
+	}
+}
+
+// TransformFaces combines the faces of a split, flip, double-faced or meld
+// card into a single card. Searchable attributes, such as types and colors,
+// include every face so that searches match either one.
+func TransformFaces(parts []MTGCard) brew.Card {
+	card := TransformCard(parts[0])
+	if len(parts) == 1 {
+		return card
+	}
+
+	names := []string{}
+	texts := []string{}
+	costs := []string{}
+	cmc := 0
+	for _, p := range parts {
+		face := TransformCard(p)
+		names = append(names, p.Name)
+		texts = append(texts, p.Text)
+		if p.ManaCost != "" {
+			costs = append(costs, p.ManaCost)
+		}
+		cmc += face.ConvertedCost
+
+		card.Faces = append(card.Faces, TransformFace(p))
+		card.Types = union(card.Types, face.Types)
+		card.Supertypes = union(card.Supertypes, face.Supertypes)
+		card.Subtypes = union(card.Subtypes, face.Subtypes)
+		card.Colors = union(card.Colors, face.Colors)
+		card.ColorIdentity = union(card.ColorIdentity, face.ColorIdentity)
+	}
+
+	card.Name = strings.Join(names, " // ")
+	card.Id = Slug(strings.Join(names, " "))
+	card.Text = strings.Join(texts, "\n//\n")
+
+	// Both halves of a split card can be cast, so its cost covers both
+	switch parts[0].Layout {
+	case "split", "aftermath":
+		card.ManaCost = strings.Join(costs, " // ")
+		card.ConvertedCost = cmc
+	}
+	return card
+}
+
+// Redirects maps the ids that faces of multi-face cards used to have onto
+// the id of the card they belong to
+func Redirects(cards []brew.Card) map[string]string {
+	redirects := map[string]string{}
+	for _, c := range cards {
+		for _, f := range c.Faces {
+			id := Slug(f.Name)
+			if _, found := redirects[id]; !found && id != c.Id {
+				redirects[id] = c.Id
+			}
+		}
+	}
+	return redirects
+}
+
+type byRelease struct {
+	codes      []string
+	collection MTGCollection
+}
+
+func (b byRelease) Len() int      { return len(b.codes) }
+func (b byRelease) Swap(i, j int) { b.codes[i], b.codes[j] = b.codes[j], b.codes[i] }
+func (b byRelease) Less(i, j int) bool {
+	ri, rj := b.collection[b.codes[i]].Released, b.collection[b.codes[j]].Released
+	if ri != rj {
+		return ri < rj
 	}
+	return b.codes[i] < b.codes[j]
 }
 
 func TransformCollection(collection MTGCollection) ([]brew.Set, []brew.Card) {
 	cards := []brew.Card{}
 	ids := map[string]brew.Card{}
+	released := map[string]string{}
 	editions := []brew.Edition{}
 	sets := []brew.Set{}
 
-	for _, set := range collection {
+	// Sets are read in release order so that the output doesn't depend on
+	// map iteration order
+	codes := []string{}
+	for code := range collection {
+		codes = append(codes, code)
+	}
+	sort.Sort(byRelease{codes, collection})
+
+	for _, code := range codes {
+		set := collection[code]
 		if strings.HasPrefix(set.Name, "p") {
 			continue
 		}
 
 		sets = append(sets, TransformSet(set))
 
+		faces := map[string]MTGCard{}
 		for _, card := range set.Cards {
-			newcard := TransformCard(card)
+			faces[card.Name] = card
+		}
+
+		for _, card := range set.Cards {
+			// Multi-face cards are added once, using their front face
+			names := FaceNames(card)
+			if len(names) == 0 || names[0] != card.Name {
+				continue
+			}
+			parts := []MTGCard{card}
+			for _, name := range names[1:] {
+				if part, found := faces[name]; found {
+					parts = append(parts, part)
+				}
+			}
+
+			newcard := TransformFaces(parts)
 			newedition := TransformEdition(set, card)
+			newedition.CardId = newcard.Id
+			for _, part := range parts[1:] {
+				if part.MultiverseId != 0 && part.MultiverseId != card.MultiverseId {
+					newedition.FaceIds = append(newedition.FaceIds, part.MultiverseId)
+				}
+			}
 
 			if _, found := ids[newcard.Id]; !found {
 				ids[newcard.Id] = newcard
 				cards = append(cards, newcard)
 			}
 
+			if first, found := released[newcard.Id]; set.Released != "" && (!found || set.Released < first) {
+				released[newcard.Id] = set.Released
+			}
+
 			editions = append(editions, newedition)
 		}
 	}
 
+	// Editions are listed newest first, so a card's first edition is its
+	// latest printing
 	for i, c := range cards {
-		for _, edition := range editions {
-			if edition.CardId == c.Id {
-				cards[i].Editions = append(cards[i].Editions, edition)
+		cards[i].Released = released[c.Id]
+		for j := len(editions) - 1; j >= 0; j-- {
+			if editions[j].CardId == c.Id {
+				cards[i].Editions = append(cards[i].Editions, editions[j])
 			}
 		}
 	}
@@ -185,7 +412,13 @@ func fetchCardIDs(ctx context.Context, db *cql.DB) ([]string, error) {
 }
 
 const queryInsertSet = `
-INSERT INTO sets (id, name, border, type) VALUES ($1, $2, $3, $4)
+INSERT INTO sets (id, name, border, type, released, block, size)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+const queryUpdateSet = `
+UPDATE sets SET (name, border, type, released, block, size) = ($1, $2, $3, $4, $5, $6)
+WHERE id = $7
 `
 
 const queryInsertCard = `
@@ -193,10 +426,15 @@ INSERT INTO cards (
   id, name, record, rules, mana_cost, cmc,
   power, toughness, loyalty, multicolor, rarities,
   types, subtypes, supertypes, colors, sets,
-  formats, status, mids
+  formats, status, mids, color_identity, released,
+  search
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-  $11, $12, $13, $14, $15, $16, $17, $18, $19
+  $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+  $21,
+  setweight(to_tsvector('english', $22), 'A') ||
+  setweight(to_tsvector('english', $23), 'B') ||
+  setweight(to_tsvector('english', $24), 'C')
 )
 `
 
@@ -205,12 +443,33 @@ UPDATE cards SET (
   name, record, rules, mana_cost, cmc,
   power, toughness, loyalty, multicolor, rarities,
   types, subtypes, supertypes, colors, sets,
-  formats, status, mids
+  formats, status, mids, color_identity, released,
+  search
 ) = (
   $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-  $11, $12, $13, $14, $15, $16, $17, $18
+  $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+  setweight(to_tsvector('english', $21), 'A') ||
+  setweight(to_tsvector('english', $22), 'B') ||
+  setweight(to_tsvector('english', $23), 'C')
 )
-WHERE id = $19
+WHERE id = $24
+`
+
+const queryDeleteRulings = `
+DELETE FROM rulings WHERE card_id = $1
+`
+
+const queryInsertRuling = `
+INSERT INTO rulings (card_id, date, text) VALUES ($1, $2, $3)
+`
+
+const queryDeleteCard = `
+DELETE FROM cards WHERE id = $1
+`
+
+const queryUpsertRedirect = `
+INSERT INTO card_redirects (id, card_id) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET card_id = EXCLUDED.card_id
 `
 
 func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error {
@@ -218,7 +477,7 @@ func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error
 	sets, cards := TransformCollection(collection)
 
 	// Load the current cards and sets
-	currentSets, err := r.GetSets(ctx)
+	currentSets, err := r.GetSets(ctx, brew.SetSearch{})
 	if err != nil {
 		return err
 	}
@@ -233,10 +492,13 @@ func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error
 		return err
 	}
 	for _, s := range sets {
+		released := sql.NullString{String: s.Released, Valid: s.Released != ""}
+		var err error
 		if existingSet(currentSets, s.Id) {
-			continue
+			_, err = tx.Exec(queryUpdateSet, s.Name, s.Border, s.Type, released, s.Block, s.Size, s.Id)
+		} else {
+			_, err = tx.Exec(queryInsertSet, s.Id, s.Name, s.Border, s.Type, released, s.Block, s.Size)
 		}
-		_, err := tx.Exec(queryInsertSet, s.Id, s.Name, s.Border, s.Type)
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("error intserting set %+v %s", s, err)
@@ -245,7 +507,10 @@ func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error
 
 	i := 0
 	for _, c := range cards {
-		blob, err := json.Marshal(c)
+		// Rulings are stored separately and only included on request
+		record := c
+		record.Rulings = nil
+		blob, err := json.Marshal(record)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -258,7 +523,9 @@ func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error
 				sarray(c.Subtypes), sarray(c.Supertypes),
 				sarray(c.Colors), sarray(c.Sets()),
 				sarray(c.Formats()), sarray(c.Status()),
-				sarray(c.MultiverseIds()), c.Id)
+				sarray(c.MultiverseIds()), sarray(c.ColorIdentity),
+				sql.NullString{String: c.Released, Valid: c.Released != ""},
+				c.Name, c.Text, c.Flavors(), c.Id)
 		} else {
 			_, err = tx.Exec(queryInsertCard,
 				c.Id, c.Name, blob, c.Text, c.ManaCost, c.ConvertedCost,
@@ -267,14 +534,49 @@ func CreateCollection(db *cql.DB, r brew.Reader, collection MTGCollection) error
 				sarray(c.Subtypes), sarray(c.Supertypes),
 				sarray(c.Colors), sarray(c.Sets()),
 				sarray(c.Formats()), sarray(c.Status()),
-				sarray(c.MultiverseIds()))
+				sarray(c.MultiverseIds()), sarray(c.ColorIdentity),
+				sql.NullString{String: c.Released, Valid: c.Released != ""},
+				c.Name, c.Text, c.Flavors())
 		}
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("error inserting / updating card %+v %s", c, err)
 		}
+		if _, err := tx.Exec(queryDeleteRulings, c.Id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error deleting rulings for card %s %s", c.Id, err)
+		}
+		for _, r := range c.Rulings {
+			_, err := tx.Exec(queryInsertRuling, c.Id,
+				sql.NullString{String: r.Date, Valid: r.Date != ""}, r.Text)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error inserting ruling %+v %s", r, err)
+			}
+		}
 		i += 1
 	}
+
+	// Faces of multi-face cards used to be stored as cards of their own
+	ids := map[string]bool{}
+	for _, c := range cards {
+		ids[c.Id] = true
+	}
+	for from, to := range Redirects(cards) {
+		if ids[from] {
+			continue
+		}
+		for _, query := range []string{queryDeleteCard, queryDeleteRulings} {
+			if _, err := tx.Exec(query, from); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error removing card %s %s", from, err)
+			}
+		}
+		if _, err := tx.Exec(queryUpsertRedirect, from, to); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error adding redirect from %s to %s %s", from, to, err)
+		}
+	}
 	return tx.Commit()
 }
 
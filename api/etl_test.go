@@ -0,0 +1,163 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestColorIdentity(t *testing.T) {
+	for _, test := range []struct {
+		card     MTGCard
+		identity []string
+	}{
+		{MTGCard{ManaCost: "{2}{R}", Colors: []string{"Red"}}, []string{"red"}},
+		{MTGCard{ManaCost: "{W/U}{W/U}"}, []string{"blue", "white"}},
+		{MTGCard{ManaCost: "{1}{G/P}"}, []string{"green"}},
+		{MTGCard{ManaCost: "{2/B}"}, []string{"black"}},
+		{MTGCard{ManaCost: "{HR}"}, []string{"red"}},
+		{MTGCard{Text: "{T}: Add {B} or {G} to your mana pool."}, []string{"black", "green"}},
+		{MTGCard{Text: "Extort (Whenever you cast a spell, you may pay {W/B}.)"}, []string{}},
+		{MTGCard{ManaCost: "{3}", Text: "{T}: Add {C}. {X}: Untap this."}, []string{}},
+		{MTGCard{Colors: []string{"Blue"}}, []string{"blue"}},
+	} {
+		identity := ColorIdentity(test.card)
+		if !reflect.DeepEqual(identity, test.identity) {
+			t.Errorf("Expected %v not %v for %+v", test.identity, identity, test.card)
+		}
+	}
+}
+
+func TestTransformCardRulings(t *testing.T) {
+	card := TransformCard(MTGCard{
+		Name: "Time Vault",
+		Rulings: []MTGRuling{
+			{Date: "2004-10-04", Text: "This is not a mana ability."},
+		},
+	})
+	if len(card.Rulings) != 1 || card.Rulings[0].Date != "2004-10-04" {
+		t.Errorf("Expected one ruling, not %+v", card.Rulings)
+	}
+}
+
+func TestTransformCollectionFaces(t *testing.T) {
+	collection := MTGCollection{
+		"APC": MTGSet{
+			Name: "Apocalypse",
+			Code: "APC",
+			Cards: []MTGCard{
+				{Name: "Fire", Names: []string{"Fire", "Ice"}, Layout: "split", ManaCost: "{1}{R}",
+					ConvertedCost: 2, Colors: []string{"Red"}, Types: []string{"Instant"}, MultiverseId: 27165},
+				{Name: "Ice", Names: []string{"Fire", "Ice"}, Layout: "split", ManaCost: "{1}{U}",
+					ConvertedCost: 2, Colors: []string{"Blue"}, Types: []string{"Instant"}, MultiverseId: 27165},
+			},
+		},
+		"ISD": MTGSet{
+			Name: "Innistrad",
+			Code: "ISD",
+			Cards: []MTGCard{
+				{Name: "Delver of Secrets", Names: []string{"Delver of Secrets", "Insectile Aberration"},
+					Layout: "double-faced", ManaCost: "{U}", ConvertedCost: 1, Colors: []string{"Blue"},
+					Types: []string{"Creature"}, Subtypes: []string{"Human", "Wizard"}, MultiverseId: 226749},
+				{Name: "Insectile Aberration", Names: []string{"Delver of Secrets", "Insectile Aberration"},
+					Layout: "double-faced", Colors: []string{"Blue"}, Types: []string{"Creature"},
+					Subtypes: []string{"Human", "Insect"}, MultiverseId: 226755},
+			},
+		},
+	}
+
+	_, cards := TransformCollection(collection)
+	if len(cards) != 2 {
+		t.Fatalf("Expected two cards, not %d", len(cards))
+	}
+
+	byId := map[string]brew.Card{}
+	for _, c := range cards {
+		byId[c.Id] = c
+	}
+
+	fire, ok := byId["fire-ice"]
+	if !ok {
+		t.Fatalf("Expected a fire-ice card, not %v", byId)
+	}
+	if fire.Name != "Fire // Ice" || len(fire.Faces) != 2 {
+		t.Errorf("Expected Fire // Ice with two faces, not %s with %d", fire.Name, len(fire.Faces))
+	}
+	if fire.ManaCost != "{1}{R} // {1}{U}" || fire.ConvertedCost != 4 {
+		t.Errorf("Expected both halves in the cost, not %s (%d)", fire.ManaCost, fire.ConvertedCost)
+	}
+	if !reflect.DeepEqual(fire.Colors, []string{"blue", "red"}) {
+		t.Errorf("Expected blue and red, not %v", fire.Colors)
+	}
+	if len(fire.Editions) != 1 {
+		t.Errorf("Expected one edition, not %d", len(fire.Editions))
+	}
+
+	delver := byId["delver-of-secrets-insectile-aberration"]
+	if !reflect.DeepEqual(delver.Subtypes, []string{"human", "insect", "wizard"}) {
+		t.Errorf("Expected subtypes from both faces, not %v", delver.Subtypes)
+	}
+	if delver.ManaCost != "{U}" {
+		t.Errorf("Expected the front face cost, not %s", delver.ManaCost)
+	}
+	if len(delver.Editions) != 1 || !reflect.DeepEqual(delver.Editions[0].FaceIds, []int{226755}) {
+		t.Errorf("Expected the back face multiverse id, not %+v", delver.Editions)
+	}
+
+	redirects := Redirects(cards)
+	expected := map[string]string{
+		"fire":                 "fire-ice",
+		"ice":                  "fire-ice",
+		"delver-of-secrets":    "delver-of-secrets-insectile-aberration",
+		"insectile-aberration": "delver-of-secrets-insectile-aberration",
+	}
+	if !reflect.DeepEqual(redirects, expected) {
+		t.Errorf("Expected %v not %v", expected, redirects)
+	}
+}
+
+func TestTransformCollectionEditionOrder(t *testing.T) {
+	bolt := func(id int) []MTGCard {
+		return []MTGCard{{Name: "Lightning Bolt", Types: []string{"Instant"}, MultiverseId: id}}
+	}
+	collection := MTGCollection{
+		"M10": MTGSet{Name: "Magic 2010", Code: "M10", Released: "2009-07-17", Cards: bolt(191089)},
+		"LEA": MTGSet{Name: "Limited Edition Alpha", Code: "LEA", Released: "1993-08-05", Cards: bolt(209)},
+		"M11": MTGSet{Name: "Magic 2011", Code: "M11", Released: "2010-07-16", Cards: bolt(205979)},
+	}
+
+	for n := 0; n < 10; n++ {
+		_, cards := TransformCollection(collection)
+		if len(cards) != 1 {
+			t.Fatalf("Expected one card, not %d", len(cards))
+		}
+		ids := []int{}
+		for _, e := range cards[0].Editions {
+			ids = append(ids, e.MultiverseId)
+		}
+		if expected := []int{205979, 191089, 209}; !reflect.DeepEqual(ids, expected) {
+			t.Fatalf("Expected editions newest first %v, not %v", expected, ids)
+		}
+		if cards[0].Released != "1993-08-05" {
+			t.Errorf("Expected the first release date, not %s", cards[0].Released)
+		}
+	}
+}
+
+func TestFaceNames(t *testing.T) {
+	names := []string{"Bruna, the Fading Light", "Gisela, the Broken Blade", "Brisela, Voice of Nightmares"}
+
+	front := FaceNames(MTGCard{Name: names[1], Names: names, Layout: "meld"})
+	if !reflect.DeepEqual(front, []string{names[1], names[2]}) {
+		t.Errorf("Expected a meld card to have its own front and the shared back, not %v", front)
+	}
+
+	if back := FaceNames(MTGCard{Name: names[2], Names: names, Layout: "meld"}); back != nil {
+		t.Errorf("Expected the melded back face not to be a card, not %v", back)
+	}
+
+	if normal := FaceNames(MTGCard{Name: "Time Vault", Layout: "normal"}); len(normal) != 1 {
+		t.Errorf("Expected a normal card to have one face, not %v", normal)
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"goji.io/pat"
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// An exporter writes a deck in a format another client can import
+type exporter struct {
+	contentType string
+	extension   string
+	write       func(brew.Deck) ([]byte, error)
+}
+
+var exporters = map[string]exporter{
+	"mtgo":       {"text/plain; charset=utf-8", "dec", ExportMTGO},
+	"arena":      {"text/plain; charset=utf-8", "txt", ExportArena},
+	"cockatrice": {"application/xml; charset=utf-8", "cod", ExportCockatrice},
+	"csv":        {"text/csv; charset=utf-8", "csv", ExportCSV},
+}
+
+func deckBoard(d brew.Deck, board string) []brew.DeckCard {
+	cards := []brew.DeckCard{}
+	for _, c := range d.Cards {
+		if c.Board == board {
+			cards = append(cards, c)
+		}
+	}
+	return cards
+}
+
+// exportName is the name other programs know a card by. Split cards keep
+// both halves, but transform, flip and meld cards only use their front face.
+func exportName(c brew.DeckCard) string {
+	if len(c.Card.Faces) < 2 {
+		return c.Card.Name
+	}
+	if e := c.Edition(); e != nil && (e.Layout == "split" || e.Layout == "aftermath") {
+		return c.Card.Name
+	}
+	return c.Card.Faces[0].Name
+}
+
+// chosenEdition returns the printing picked for a card, or nil if the deck
+// didn't pick one
+func chosenEdition(c brew.DeckCard) *brew.Edition {
+	if c.MultiverseId == 0 {
+		return nil
+	}
+	return c.Edition()
+}
+
+// ExportMTGO writes a .dec file. MTGO keeps commanders in the sideboard and
+// writes split cards with a single slash.
+func ExportMTGO(d brew.Deck) ([]byte, error) {
+	var b bytes.Buffer
+	name := func(c brew.DeckCard) string {
+		return strings.Replace(exportName(c), " // ", "/", -1)
+	}
+	for _, c := range deckBoard(d, brew.BoardMain) {
+		fmt.Fprintf(&b, "%d %s\n", c.Quantity, name(c))
+	}
+	side := append(deckBoard(d, brew.BoardCommander), deckBoard(d, brew.BoardSide)...)
+	if len(side) > 0 {
+		b.WriteString("\n")
+	}
+	for _, c := range side {
+		fmt.Fprintf(&b, "SB: %d %s\n", c.Quantity, name(c))
+	}
+	return b.Bytes(), nil
+}
+
+// ExportArena writes each card with the set code and collector number of
+// its chosen printing, grouped under section headers
+func ExportArena(d brew.Deck) ([]byte, error) {
+	var b bytes.Buffer
+	for _, section := range []struct {
+		header string
+		board  string
+	}{
+		{"Commander", brew.BoardCommander},
+		{"Deck", brew.BoardMain},
+		{"Sideboard", brew.BoardSide},
+	} {
+		cards := deckBoard(d, section.board)
+		if len(cards) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(section.header + "\n")
+		for _, c := range cards {
+			line := fmt.Sprintf("%d %s", c.Quantity, exportName(c))
+			if e := chosenEdition(c); e != nil {
+				line += " (" + e.SetId + ")"
+				if e.Number != "" {
+					line += " " + e.Number
+				}
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.Bytes(), nil
+}
+
+type cockatriceCard struct {
+	Number int    `xml:"number,attr"`
+	Name   string `xml:"name,attr"`
+}
+
+type cockatriceZone struct {
+	Name  string           `xml:"name,attr"`
+	Cards []cockatriceCard `xml:"card"`
+}
+
+type cockatriceDeck struct {
+	XMLName  xml.Name         `xml:"cockatrice_deck"`
+	Version  int              `xml:"version,attr"`
+	Name     string           `xml:"deckname"`
+	Comments string           `xml:"comments"`
+	Zones    []cockatriceZone `xml:"zone"`
+}
+
+// ExportCockatrice writes a .cod file. Cockatrice has no commander zone, so
+// commanders are part of the main deck.
+func ExportCockatrice(d brew.Deck) ([]byte, error) {
+	deck := cockatriceDeck{Version: 1, Name: d.Name}
+	for _, zone := range []struct {
+		name   string
+		boards []string
+	}{
+		{"main", []string{brew.BoardCommander, brew.BoardMain}},
+		{"side", []string{brew.BoardSide}},
+	} {
+		z := cockatriceZone{Name: zone.name}
+		for _, board := range zone.boards {
+			for _, c := range deckBoard(d, board) {
+				z.Cards = append(z.Cards, cockatriceCard{Number: c.Quantity, Name: exportName(c)})
+			}
+		}
+		if len(z.Cards) > 0 {
+			deck.Zones = append(deck.Zones, z)
+		}
+	}
+	blob, err := xml.MarshalIndent(deck, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(blob, '\n')...), nil
+}
+
+// Deck names can contain anything, but filenames shouldn't
+var exportFilename = regexp.MustCompile(`[^a-z0-9_-]`)
+
+func dollars(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// ExportCSV writes a row for every card with its chosen printing and latest
+// median price in dollars. Cards are named the way other programs know them.
+// Cards without a chosen printing have no set and are priced at their newest
+// printing. The price is empty when it isn't known.
+func ExportCSV(d brew.Deck) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"quantity", "name", "board", "set", "number", "multiverse_id", "price"})
+	for _, c := range d.Cards {
+		var set, number, mid, price string
+		if e := chosenEdition(c); e != nil {
+			set, number, mid = e.SetId, e.Number, strconv.Itoa(e.MultiverseId)
+		}
+		if e := c.Edition(); e != nil && e.Price != nil {
+			price = dollars(e.Price.Average)
+		}
+		w.Write([]string{strconv.Itoa(c.Quantity), exportName(c), c.Board, set, number, mid, price})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+func (a *API) HandleExportDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	e, ok := exporters[format]
+	if !ok {
+		JSON(w, http.StatusBadRequest, Errors(fmt.Sprintf("The export format '%s' is not recognized", format)))
+		return
+	}
+	deck, err := a.d.GetDeck(ctx, pat.Param(ctx, "id"))
+	if err == brew.ErrDeckNotFound {
+		JSON(w, http.StatusNotFound, Errors("Deck not found"))
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching deck"))
+		return
+	}
+	// An export without some of the deck's cards would look complete, so
+	// refuse to write one
+	if len(deck.Missing) > 0 {
+		JSON(w, http.StatusConflict, Errors(missingCards(deck.Missing)...))
+		return
+	}
+	blob, err := e.write(deck)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error exporting deck"))
+		return
+	}
+	filename := exportFilename.ReplaceAllString(brew.Slug(deck.Name), "")
+	if filename == "" {
+		filename = deck.Id
+	}
+	w.Header().Set("Content-Type", e.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, e.extension))
+	w.WriteHeader(http.StatusOK)
+	w.Write(blob)
+}
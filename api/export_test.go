@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"goji.io/pattern"
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func exportDeck() brew.Deck {
+	price := &brew.Price{Low: 10, Average: 125, High: 300}
+	return brew.Deck{
+		Id:   "abc",
+		Name: "Boros Fire",
+		Cards: []brew.DeckCard{
+			{Quantity: 1, Board: brew.BoardCommander, MultiverseId: 366347, Card: brew.Card{Name: "Aurelia, the Warleader",
+				Editions: []brew.Edition{{SetId: "GTC", Number: "143", MultiverseId: 366347}}}},
+			{Quantity: 4, Board: brew.BoardMain, MultiverseId: 2, Card: brew.Card{Name: "Lightning Bolt",
+				Editions: []brew.Edition{
+					{SetId: "LEA", Number: "161", MultiverseId: 1},
+					{SetId: "M10", Number: "146", MultiverseId: 2, Price: price},
+				}}},
+			{Quantity: 2, Board: brew.BoardMain, Card: brew.Card{Name: "Fire // Ice",
+				Faces:    []brew.Face{{Name: "Fire"}, {Name: "Ice"}},
+				Editions: []brew.Edition{{SetId: "APC", MultiverseId: 27165, Layout: "split", Price: &brew.Price{Average: 50}}}}},
+			{Quantity: 4, Board: brew.BoardMain, MultiverseId: 226749, Card: brew.Card{Name: "Delver of Secrets // Insectile Aberration",
+				Faces:    []brew.Face{{Name: "Delver of Secrets"}, {Name: "Insectile Aberration"}},
+				Editions: []brew.Edition{{SetId: "ISD", Number: "51a", MultiverseId: 226749, Layout: "double-faced"}}}},
+			{Quantity: 3, Board: brew.BoardSide, Card: brew.Card{Name: "Smash to Smithereens"}},
+		},
+	}
+}
+
+func TestExportMTGO(t *testing.T) {
+	blob, _ := ExportMTGO(exportDeck())
+	expected := "4 Lightning Bolt\n2 Fire/Ice\n4 Delver of Secrets\n\n" +
+		"SB: 1 Aurelia, the Warleader\nSB: 3 Smash to Smithereens\n"
+	if string(blob) != expected {
+		t.Errorf("Expected %q not %q", expected, string(blob))
+	}
+}
+
+func TestExportArena(t *testing.T) {
+	blob, _ := ExportArena(exportDeck())
+	expected := "Commander\n1 Aurelia, the Warleader (GTC) 143\n\n" +
+		"Deck\n4 Lightning Bolt (M10) 146\n2 Fire // Ice\n4 Delver of Secrets (ISD) 51a\n\n" +
+		"Sideboard\n3 Smash to Smithereens\n"
+	if string(blob) != expected {
+		t.Errorf("Expected %q not %q", expected, string(blob))
+	}
+}
+
+func TestExportCockatrice(t *testing.T) {
+	blob, err := ExportCockatrice(exportDeck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<cockatrice_deck version="1">
+  <deckname>Boros Fire</deckname>
+  <comments></comments>
+  <zone name="main">
+    <card number="1" name="Aurelia, the Warleader"></card>
+    <card number="4" name="Lightning Bolt"></card>
+    <card number="2" name="Fire // Ice"></card>
+    <card number="4" name="Delver of Secrets"></card>
+  </zone>
+  <zone name="side">
+    <card number="3" name="Smash to Smithereens"></card>
+  </zone>
+</cockatrice_deck>
+`
+	if string(blob) != expected {
+		t.Errorf("Expected %q not %q", expected, string(blob))
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	blob, err := ExportCSV(exportDeck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "quantity,name,board,set,number,multiverse_id,price\n" +
+		"1,\"Aurelia, the Warleader\",commander,GTC,143,366347,\n" +
+		"4,Lightning Bolt,mainboard,M10,146,2,1.25\n" +
+		"2,Fire // Ice,mainboard,,,,0.50\n" +
+		"4,Delver of Secrets,mainboard,ISD,51a,226749,\n" +
+		"3,Smash to Smithereens,sideboard,,,,\n"
+	if string(blob) != expected {
+		t.Errorf("Expected %q not %q", expected, string(blob))
+	}
+}
+
+func TestExportFilename(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Boros Fire":        "boros-fire",
+		"R/G Tron; v2.1":    "rg-tron-v21",
+		"\"Quoted\" (test)": "quoted-test",
+	} {
+		if slug := exportFilename.ReplaceAllString(brew.Slug(name), ""); slug != expected {
+			t.Errorf("Expected %s not %s", expected, slug)
+		}
+	}
+}
+
+// savedDecks serves fixed decks without a database
+type savedDecks struct {
+	brew.DeckStore
+	decks map[string]brew.Deck
+}
+
+func (s savedDecks) GetDeck(ctx context.Context, id string) (brew.Deck, error) {
+	d, ok := s.decks[id]
+	if !ok {
+		return d, brew.ErrDeckNotFound
+	}
+	return d, nil
+}
+
+func TestHandleExportDeckMissing(t *testing.T) {
+	deck := exportDeck()
+	deck.Missing = []brew.DeckCardRef{{Quantity: 2, Board: brew.BoardSide, Id: "renamed-card"}}
+	api := &API{d: savedDecks{decks: map[string]brew.Deck{"abc": deck}}}
+
+	r, _ := http.NewRequest("GET", "/mtg/decks/abc/export?format=mtgo", nil)
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(context.Background(), pattern.Variable("id"), "abc")
+	api.HandleExportDeck(ctx, w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 not %d: %s", w.Code, w.Body.String())
+	}
+	var body ApiError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"The card 'renamed-card' in the sideboard no longer exists"}
+	if !reflect.DeepEqual(body.Errors, expected) {
+		t.Errorf("Expected %v not %v", expected, body.Errors)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -20,9 +19,7 @@ import (
 )
 
 func Slug(name string) string {
-	re := regexp.MustCompile(`[,.'"?:()]`)
-	d := strings.Replace(strings.ToLower(name), " ", "-", -1)
-	return re.ReplaceAllLiteralString(d, "")
+	return brew.Slug(name)
 }
 
 func JSON(w http.ResponseWriter, code int, val interface{}) {
@@ -41,22 +38,57 @@ func Errors(errors ...string) ApiError {
 	return ApiError{Errors: errors}
 }
 
-func LinkHeader(host string, u *url.URL, page int) string {
-	if page == 0 {
-		qstring := u.Query()
-		qstring.Set("page", "1")
-		return fmt.Sprintf("<%s%s?%s>; rel=\"next\"", host, u.Path, qstring.Encode())
-	} else {
-		qstring := u.Query()
+// LinkHeader returns links to the first, previous, next and last pages of
+// results. Links are only included for pages that exist.
+func LinkHeader(host string, u *url.URL, page, perPage, total int) string {
+	last := 0
+	if total > 0 {
+		last = (total - 1) / perPage
+	}
 
-		qstring.Set("page", strconv.Itoa(page-1))
-		prev := fmt.Sprintf("<%s%s?%s>; rel=\"prev\"", host, u.Path, qstring.Encode())
+	qstring := u.Query()
+	link := func(page int, rel string) string {
+		qstring.Set("page", strconv.Itoa(page))
+		return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", host, u.Path, qstring.Encode(), rel)
+	}
 
-		qstring.Set("page", strconv.Itoa(page+1))
-		next := fmt.Sprintf("<%s%s?%s>; rel=\"next\"", host, u.Path, qstring.Encode())
+	links := []string{}
+	if page > 0 {
+		prev := page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, link(0, "first"))
+		links = append(links, link(prev, "prev"))
+	}
+	if page < last {
+		links = append(links, link(page+1, "next"))
+		links = append(links, link(last, "last"))
+	}
+	return strings.Join(links, ", ")
+}
 
-		return prev + ", " + next
+// CursorLinkHeader returns links to the first page of results and to the
+// page after the given cursor. Links are only included for pages that exist.
+func CursorLinkHeader(host string, u *url.URL, next *brew.Cursor) string {
+	qstring := u.Query()
+	link := func(rel string) string {
+		if len(qstring) == 0 {
+			return fmt.Sprintf("<%s%s>; rel=\"%s\"", host, u.Path, rel)
+		}
+		return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", host, u.Path, qstring.Encode(), rel)
+	}
+
+	links := []string{}
+	if qstring.Get("cursor") != "" {
+		qstring.Del("cursor")
+		links = append(links, link("first"))
+	}
+	if next != nil {
+		qstring.Set("cursor", next.Encode())
+		links = append(links, link("next"))
 	}
+	return strings.Join(links, ", ")
 }
 
 type ApiError struct {
@@ -65,6 +97,7 @@ type ApiError struct {
 
 type API struct {
 	c    brew.Reader
+	d    brew.DeckStore
 	host string
 }
 
@@ -76,18 +109,66 @@ func (a *API) apiBase() string {
 	}
 }
 
+// included reports whether the include parameter asks for a field
+func included(r *http.Request, field string) bool {
+	for _, value := range r.URL.Query()["include"] {
+		for _, f := range strings.Split(value, ",") {
+			if strings.TrimSpace(f) == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *API) includeRulings(ctx context.Context, cards []brew.Card) error {
+	ids := []string{}
+	for _, c := range cards {
+		ids = append(ids, c.Id)
+	}
+	rulings, err := a.c.GetRulings(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range cards {
+		cards[i].Rulings = rulings[cards[i].Id]
+	}
+	return nil
+}
+
 func (a *API) HandleCards(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	s, err, errors := ParseSearch(r.URL)
 	if err != nil {
 		JSON(w, http.StatusBadRequest, Errors(errors...))
 		return
 	}
-	cards, err := a.c.GetCards(ctx, s)
+	cards, next, err := a.c.GetCardPage(ctx, s)
 	if err != nil {
 		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
 		return
 	}
-	w.Header().Set("Link", LinkHeader(a.apiBase(), r.URL, s.Page))
+	if included(r, "rulings") {
+		if err := a.includeRulings(ctx, cards); err != nil {
+			JSON(w, http.StatusInternalServerError, Errors("Error fetching rulings"))
+			return
+		}
+	}
+	total, err := a.c.CountCards(ctx, s)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error counting cards"))
+		return
+	}
+
+	// Clients following cursors get cursor links, everyone else gets page
+	// links. An empty cursor parameter starts from the first page.
+	link := LinkHeader(a.apiBase(), r.URL, s.Page, s.Limit, total)
+	if _, ok := r.URL.Query()["cursor"]; ok && r.URL.Query().Get("page") == "" {
+		link = CursorLinkHeader(a.apiBase(), r.URL, next)
+	}
+	if link != "" {
+		w.Header().Set("Link", link)
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	JSON(w, http.StatusOK, cards)
 }
 
@@ -105,17 +186,116 @@ func (a *API) HandleRandomCard(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 }
 
+// redirectCard sends requests using the old id of a face of a multi-face
+// card to the card it belongs to
+func (a *API) redirectCard(ctx context.Context, w http.ResponseWriter, r *http.Request, suffix string) bool {
+	to, err := a.c.GetCardRedirect(ctx, pat.Param(ctx, "id"))
+	if err != nil {
+		return false
+	}
+	location := "/mtg/cards/" + to + suffix
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, location, http.StatusMovedPermanently)
+	return true
+}
+
 func (a *API) HandleCard(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	card, err := a.c.GetCard(ctx, pat.Param(ctx, "id"))
 	if err != nil {
-		JSON(w, http.StatusNotFound, Errors("Card not found"))
+		if !a.redirectCard(ctx, w, r, "") {
+			JSON(w, http.StatusNotFound, Errors("Card not found"))
+		}
 		return
 	}
+	if included(r, "rulings") {
+		cards := []brew.Card{card}
+		if err := a.includeRulings(ctx, cards); err != nil {
+			JSON(w, http.StatusInternalServerError, Errors("Error fetching rulings"))
+			return
+		}
+		card = cards[0]
+	}
 	JSON(w, http.StatusOK, card)
 }
 
+func (a *API) HandleRulings(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	card, err := a.c.GetCard(ctx, pat.Param(ctx, "id"))
+	if err != nil {
+		if !a.redirectCard(ctx, w, r, "/rulings") {
+			JSON(w, http.StatusNotFound, Errors("Card not found"))
+		}
+		return
+	}
+	rulings, err := a.c.GetRulings(ctx, []string{card.Id})
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching rulings"))
+		return
+	}
+	if rulings[card.Id] == nil {
+		JSON(w, http.StatusOK, []brew.Ruling{})
+		return
+	}
+	JSON(w, http.StatusOK, rulings[card.Id])
+}
+
+func (a *API) HandleEditionPrices(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	s, err, errors := ParsePriceHistory(r.URL)
+	if err != nil {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	id := pat.Param(ctx, "multiverse_id")
+	if _, err := strconv.Atoi(id); err != nil {
+		JSON(w, http.StatusNotFound, Errors("Edition not found"))
+		return
+	}
+	cards, err := a.c.GetCards(ctx, brew.Search{MultiverseIDs: []string{id}, Limit: 1})
+	if err != nil || len(cards) == 0 {
+		JSON(w, http.StatusNotFound, Errors("Edition not found"))
+		return
+	}
+	s.MultiverseIDs = []string{id}
+	history, err := a.c.GetPriceHistory(ctx, s)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching prices"))
+		return
+	}
+	JSON(w, http.StatusOK, history)
+}
+
+func (a *API) HandleCardPrices(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	s, err, errors := ParsePriceHistory(r.URL)
+	if err != nil {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	card, err := a.c.GetCard(ctx, pat.Param(ctx, "id"))
+	if err != nil {
+		if !a.redirectCard(ctx, w, r, "/prices") {
+			JSON(w, http.StatusNotFound, Errors("Card not found"))
+		}
+		return
+	}
+	for _, e := range card.Editions {
+		s.MultiverseIDs = append(s.MultiverseIDs, strconv.Itoa(e.MultiverseId))
+	}
+	history, err := a.c.GetPriceHistory(ctx, s)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching prices"))
+		return
+	}
+	JSON(w, http.StatusOK, history)
+}
+
 func (a *API) HandleSets(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	sets, err := a.c.GetSets(ctx)
+	s, err, errors := ParseSetSearch(r.URL)
+	if err != nil {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	sets, err := a.c.GetSets(ctx, s)
 	if err != nil {
 		JSON(w, http.StatusNotFound, Errors("Sets not found"))
 	} else {
@@ -153,6 +333,20 @@ func (a *API) HandleTypeahead(ctx context.Context, w http.ResponseWriter, r *htt
 	JSON(w, http.StatusOK, cards)
 }
 
+func (a *API) HandleResolve(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		JSON(w, http.StatusBadRequest, Errors("The name parameter is required"))
+		return
+	}
+	resolution, err := a.c.ResolveCardName(ctx, name)
+	if err != nil {
+		JSON(w, http.StatusNotFound, Errors("Can't find a card that matches that name"))
+		return
+	}
+	JSON(w, http.StatusOK, resolution)
+}
+
 func NotFound(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusNotFound, Errors("No endpoint here"))
 }
@@ -160,7 +354,7 @@ func NotFound(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 type term int
 
 func New(cfg *config.Config, client brew.Reader) http.Handler {
-	app := API{c: client, host: cfg.HostAPI}
+	app := API{c: client, d: brew.NewDeckStore(cfg, client), host: cfg.HostAPI}
 
 	mux := goji.NewMux()
 
@@ -172,8 +366,23 @@ func New(cfg *config.Config, client brew.Reader) http.Handler {
 
 	mux.HandleFuncC(pat.Get("/mtg/cards"), app.HandleCards)
 	mux.HandleFuncC(pat.Get("/mtg/cards/typeahead"), app.HandleTypeahead)
+	mux.HandleFuncC(pat.Get("/mtg/cards/resolve"), app.HandleResolve)
+	mux.HandleFuncC(pat.Post("/mtg/cards/collection"), app.HandleCollection)
 	mux.HandleFuncC(pat.Get("/mtg/cards/random"), app.HandleRandomCard)
 	mux.HandleFuncC(pat.Get("/mtg/cards/:id"), app.HandleCard)
+	mux.HandleFuncC(pat.Get("/mtg/cards/:id/rulings"), app.HandleRulings)
+	mux.HandleFuncC(pat.Get("/mtg/cards/:id/prices"), app.HandleCardPrices)
+	mux.HandleFuncC(pat.Get("/mtg/editions/:multiverse_id/prices"), app.HandleEditionPrices)
+	mux.HandleFuncC(pat.Post("/mtg/decks/parse"), app.HandleParseDeck)
+	mux.HandleFuncC(pat.Post("/mtg/decks/validate"), app.HandleValidateDeck)
+	mux.HandleFuncC(pat.Post("/mtg/decks/stats"), app.HandleDeckStats)
+	mux.HandleFuncC(pat.Post("/mtg/decks/odds"), app.HandleDeckOdds)
+	mux.HandleFuncC(pat.Post("/mtg/decks/diff"), app.HandleDiffDecks)
+	mux.HandleFuncC(pat.Post("/mtg/decks"), app.HandleCreateDeck)
+	mux.HandleFuncC(pat.Get("/mtg/decks/:id"), app.HandleDeck)
+	mux.HandleFuncC(pat.Put("/mtg/decks/:id"), app.HandleUpdateDeck)
+	mux.HandleFuncC(pat.Delete("/mtg/decks/:id"), app.HandleDeleteDeck)
+	mux.HandleFuncC(pat.Get("/mtg/decks/:id/export"), app.HandleExportDeck)
 	mux.HandleFuncC(pat.Get("/mtg/sets"), app.HandleSets)
 	mux.HandleFuncC(pat.Get("/mtg/sets/:id"), app.HandleSet)
 	mux.HandleFuncC(pat.Get("/mtg/colors"), app.HandleTerm(client.GetColors))
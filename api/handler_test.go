@@ -9,27 +9,111 @@ import (
 	"net/url"
 	"testing"
 
+	"golang.org/x/net/context"
+
 	"github.com/kyleconroy/deckbrew/brew"
 	"github.com/kyleconroy/deckbrew/config"
 )
 
 func TestLinkHeader(t *testing.T) {
 	url, _ := url.Parse("/cards?foo=bar")
-	header := LinkHeader("http://localhost:3000", url, 0)
-	expected := "<http://localhost:3000/cards?foo=bar&page=1>; rel=\"next\""
+	header := LinkHeader("http://localhost:3000", url, 0, 100, 250)
+	expected := "<http://localhost:3000/cards?foo=bar&page=1>; rel=\"next\", <http://localhost:3000/cards?foo=bar&page=2>; rel=\"last\""
 
 	if header != expected {
 		t.Errorf("Expected %s not %s", expected, header)
 	}
 
 	url, _ = url.Parse("/cards?foo=bar&page=1")
-	header = LinkHeader("http://localhost:3000", url, 1)
-	expected = "<http://localhost:3000/cards?foo=bar&page=0>; rel=\"prev\", <http://localhost:3000/cards?foo=bar&page=2>; rel=\"next\""
+	header = LinkHeader("http://localhost:3000", url, 1, 100, 250)
+	expected = "<http://localhost:3000/cards?foo=bar&page=0>; rel=\"first\", <http://localhost:3000/cards?foo=bar&page=0>; rel=\"prev\", <http://localhost:3000/cards?foo=bar&page=2>; rel=\"next\", <http://localhost:3000/cards?foo=bar&page=2>; rel=\"last\""
+
+	if header != expected {
+		t.Errorf("Expected %s not %s", expected, header)
+	}
+
+	url, _ = url.Parse("/cards?foo=bar&page=2")
+	header = LinkHeader("http://localhost:3000", url, 2, 100, 250)
+	expected = "<http://localhost:3000/cards?foo=bar&page=0>; rel=\"first\", <http://localhost:3000/cards?foo=bar&page=1>; rel=\"prev\""
+
+	if header != expected {
+		t.Errorf("Expected %s not %s", expected, header)
+	}
+
+	url, _ = url.Parse("/cards?foo=bar")
+	header = LinkHeader("http://localhost:3000", url, 0, 100, 100)
+
+	if header != "" {
+		t.Errorf("Expected no links for a single page, not %s", header)
+	}
+}
+
+func TestCursorLinkHeader(t *testing.T) {
+	cursor := brew.Cursor{Sort: "name", Order: "asc", Id: "time-vault"}
+
+	url, _ := url.Parse("/cards?foo=bar")
+	header := CursorLinkHeader("http://localhost:3000", url, &cursor)
+	expected := "<http://localhost:3000/cards?cursor=" + cursor.Encode() + "&foo=bar>; rel=\"next\""
 
 	if header != expected {
 		t.Errorf("Expected %s not %s", expected, header)
 	}
 
+	url, _ = url.Parse("/cards?foo=bar&cursor=abc")
+	header = CursorLinkHeader("http://localhost:3000", url, nil)
+	expected = "<http://localhost:3000/cards?foo=bar>; rel=\"first\""
+
+	if header != expected {
+		t.Errorf("Expected %s not %s", expected, header)
+	}
+}
+
+// cardPageReader serves a fixed page of cards without a database
+type cardPageReader struct {
+	brew.Reader
+	total int
+	next  *brew.Cursor
+}
+
+func (c cardPageReader) GetCardPage(ctx context.Context, s brew.Search) ([]brew.Card, *brew.Cursor, error) {
+	return []brew.Card{}, c.next, nil
+}
+
+func (c cardPageReader) CountCards(ctx context.Context, s brew.Search) (int, error) {
+	return c.total, nil
+}
+
+func TestHandleCardsLinks(t *testing.T) {
+	cursor := brew.Cursor{Sort: "name", Order: "asc", Id: "time-vault"}
+	api := &API{c: cardPageReader{total: 250, next: &cursor}, host: "localhost:3000"}
+	base := "http://localhost:3000/mtg/cards"
+
+	for _, test := range []struct {
+		query string
+		link  string
+	}{
+		{"", "<" + base + "?page=1>; rel=\"next\", <" + base + "?page=2>; rel=\"last\""},
+		{"?page=1", "<" + base + "?page=0>; rel=\"first\", <" + base + "?page=0>; rel=\"prev\", " +
+			"<" + base + "?page=2>; rel=\"next\", <" + base + "?page=2>; rel=\"last\""},
+		{"?cursor=", "<" + base + "?cursor=" + cursor.Encode() + ">; rel=\"next\""},
+		{"?cursor=" + cursor.Encode(), "<" + base + ">; rel=\"first\", " +
+			"<" + base + "?cursor=" + cursor.Encode() + ">; rel=\"next\""},
+	} {
+		r, _ := http.NewRequest("GET", "/mtg/cards"+test.query, nil)
+		w := httptest.NewRecorder()
+		api.HandleCards(context.Background(), w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 not %d: %s", test.query, w.Code, w.Body.String())
+			continue
+		}
+		if link := w.Header().Get("Link"); link != test.link {
+			t.Errorf("%s: expected %s not %s", test.query, test.link, link)
+		}
+		if count := w.Header().Get("X-Total-Count"); count != "250" {
+			t.Errorf("%s: expected a total of 250 not %s", test.query, count)
+		}
+	}
 }
 
 func TestSlug(t *testing.T) {
@@ -67,9 +151,22 @@ func TestApi(t *testing.T) {
 		"/mtg/cards?rarity=mythic",
 		"/mtg/cards?rarity=basic",
 		"/mtg/cards?oracle=you+win+the+game",
+		"/mtg/cards?q=t:creature+c:rg+cmc>%3D3",
+		"/mtg/cards?power=gte:5&sort=power&order=desc",
+		"/mtg/cards?color=red&color_match=exact",
+		"/mtg/cards?identity=black&identity=green",
+		"/mtg/cards?text=draws+a+card&sort=relevance",
 		"/mtg/cards/time-vault",
+		"/mtg/cards/time-vault?include=rulings",
+		"/mtg/cards/time-vault/rulings",
+		"/mtg/cards/time-vault/prices?from=2015-01-01",
+		"/mtg/cards?name=vault&include=rulings",
 		"/mtg/cards/typeahead?q=nessian",
+		"/mtg/cards/resolve?name=jace+the+mind+scultor",
+		"/mtg/editions/12414/prices?interval=week",
 		"/mtg/sets",
+		"/mtg/sets?type=expansion&sort=released&order=desc",
+		"/mtg/sets?block=alara&from=2008-01-01&to=2009-12-31",
 		"/mtg/sets/UNH",
 		"/mtg/colors",
 		"/mtg/types",
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// A Violation is a single way a deck breaks the rules of a format. Card is
+// empty for violations that apply to the deck as a whole.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	CardId  string `json:"card_id,omitempty"`
+	Card    string `json:"card,omitempty"`
+}
+
+type ValidateRequest struct {
+	Decklist string `json:"decklist"`
+	Format   string `json:"format"`
+}
+
+type ValidationResult struct {
+	Format     string      `json:"format"`
+	Legal      bool        `json:"legal"`
+	Violations []Violation `json:"violations"`
+	Errors     []DeckError `json:"errors"`
+}
+
+func cardViolation(rule string, c *brew.Card, format string, args ...interface{}) Violation {
+	return Violation{Rule: rule, Message: fmt.Sprintf(format, args...), CardId: c.Id, Card: c.Name}
+}
+
+func hasType(types []string, t string) bool {
+	for _, s := range types {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isBasicLand(c *brew.Card) bool {
+	return hasType(c.Supertypes, "basic") && hasType(c.Types, "land")
+}
+
+// Cards such as Relentless Rats ignore the copy limit
+func anyNumberAllowed(c *brew.Card) bool {
+	return strings.Contains(strings.ToLower(c.Text), "a deck can have any number of cards named")
+}
+
+func canBeCommander(c *brew.Card) bool {
+	if hasType(c.Supertypes, "legendary") && hasType(c.Types, "creature") {
+		return true
+	}
+	return strings.Contains(strings.ToLower(c.Text), "can be your commander")
+}
+
+func hasPartner(c *brew.Card) bool {
+	for _, line := range strings.Split(strings.ToLower(c.Text), "\n") {
+		if strings.HasPrefix(line, "partner") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDeck checks a deck against the rules of a format and returns every
+// violation, in the order the cards appear in the deck
+func ValidateDeck(format string, deck []brew.DeckCard) []Violation {
+	violations := []Violation{}
+	commander := format == "commander"
+
+	// Copies are counted across zones
+	counts := map[string]int{}
+	cards := map[string]*brew.Card{}
+	order := []string{}
+	main, side := 0, 0
+	commanders := []*brew.Card{}
+
+	for i := range deck {
+		d := &deck[i]
+		if _, seen := cards[d.Card.Id]; !seen {
+			order = append(order, d.Card.Id)
+			cards[d.Card.Id] = &d.Card
+		}
+		counts[d.Card.Id] += d.Quantity
+
+		switch d.Board {
+		case brew.BoardMain:
+			main += d.Quantity
+		case brew.BoardSide:
+			side += d.Quantity
+		case brew.BoardCommander:
+			for n := 0; n < d.Quantity; n++ {
+				commanders = append(commanders, &d.Card)
+			}
+		}
+	}
+
+	for _, id := range order {
+		c := cards[id]
+		switch c.FormatMap[format] {
+		case "legal":
+		case "restricted":
+			if counts[id] > 1 {
+				violations = append(violations, cardViolation("restricted", c,
+					"%s is restricted in %s, so only one copy is allowed", c.Name, format))
+			}
+			continue
+		case "banned":
+			violations = append(violations, cardViolation("banned", c,
+				"%s is banned in %s", c.Name, format))
+			continue
+		default:
+			violations = append(violations, cardViolation("not_legal", c,
+				"%s is not legal in %s", c.Name, format))
+			continue
+		}
+
+		if isBasicLand(c) || anyNumberAllowed(c) {
+			continue
+		}
+		if commander && counts[id] > 1 {
+			violations = append(violations, cardViolation("singleton", c,
+				"Commander decks can only have one copy of %s", c.Name))
+		}
+		if !commander && counts[id] > 4 {
+			violations = append(violations, cardViolation("copy_limit", c,
+				"Decks can have at most four copies of %s, not %d", c.Name, counts[id]))
+		}
+	}
+
+	if !commander {
+		if main < 60 {
+			violations = append(violations, Violation{Rule: "deck_size",
+				Message: fmt.Sprintf("The main deck must have at least 60 cards, not %d", main)})
+		}
+		if side > 15 {
+			violations = append(violations, Violation{Rule: "sideboard_size",
+				Message: fmt.Sprintf("The sideboard can have at most 15 cards, not %d", side)})
+		}
+		if len(commanders) > 0 {
+			violations = append(violations, Violation{Rule: "commander",
+				Message: fmt.Sprintf("Only commander decks can have a commander, not %s decks", format)})
+		}
+		return violations
+	}
+	return append(violations, validateCommander(main, commanders, deck)...)
+}
+
+// Commander decks have exactly 100 cards including their commander, and every
+// card must fit within the commander's color identity
+func validateCommander(main int, commanders []*brew.Card, deck []brew.DeckCard) []Violation {
+	violations := []Violation{}
+
+	switch {
+	case len(commanders) == 0:
+		violations = append(violations, Violation{Rule: "commander",
+			Message: "Commander decks need a commander"})
+	case len(commanders) == 2 && (!hasPartner(commanders[0]) || !hasPartner(commanders[1])):
+		violations = append(violations, Violation{Rule: "commander",
+			Message: "Two commanders are only allowed when both have partner"})
+	case len(commanders) > 2:
+		violations = append(violations, Violation{Rule: "commander",
+			Message: fmt.Sprintf("Commander decks can have at most two commanders, not %d", len(commanders))})
+	}
+	for _, c := range commanders {
+		if !canBeCommander(c) {
+			violations = append(violations, cardViolation("commander", c,
+				"%s is not a legendary creature and can't be a commander", c.Name))
+		}
+	}
+
+	if total := main + len(commanders); total != 100 {
+		violations = append(violations, Violation{Rule: "deck_size",
+			Message: fmt.Sprintf("Commander decks must have exactly 100 cards, not %d", total)})
+	}
+
+	if len(commanders) == 0 {
+		return violations
+	}
+	identity := map[string]bool{}
+	for _, c := range commanders {
+		for _, color := range c.ColorIdentity {
+			identity[color] = true
+		}
+	}
+	allowed := []string{}
+	for color := range identity {
+		allowed = append(allowed, color)
+	}
+	sort.Strings(allowed)
+	if len(allowed) == 0 {
+		allowed = []string{"colorless"}
+	}
+
+	for i := range deck {
+		d := &deck[i]
+		if d.Board != brew.BoardMain {
+			continue
+		}
+		for _, color := range d.Card.ColorIdentity {
+			if !identity[color] {
+				violations = append(violations, cardViolation("color_identity", &d.Card,
+					"%s is %s, which is outside the commander's color identity of %s",
+					d.Card.Name, color, strings.Join(allowed, ", ")))
+				break
+			}
+		}
+	}
+	return violations
+}
+
+func (a *API) HandleValidateDeck(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req ValidateRequest
+	if err := readDeckRequest(w, r, &req); err != nil || strings.TrimSpace(req.Decklist) == "" {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a decklist and format"))
+		return
+	}
+	format := strings.ToLower(req.Format)
+	if !validFormats[format] {
+		JSON(w, http.StatusBadRequest, Errors(fmt.Sprintf("The format '%s' is not recognized", req.Format)))
+		return
+	}
+	deck, errors, err := a.resolveDecklist(ctx, req.Decklist)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return
+	}
+	violations := ValidateDeck(format, deck)
+	JSON(w, http.StatusOK, ValidationResult{
+		Format:     format,
+		Legal:      len(violations) == 0 && len(errors) == 0,
+		Violations: violations,
+		Errors:     errors,
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func legalCard(id string, formats map[string]string) brew.Card {
+	return brew.Card{Id: id, Name: id, Types: []string{"instant"}, FormatMap: formats}
+}
+
+func violationRules(vs []Violation) []string {
+	rules := []string{}
+	for _, v := range vs {
+		rules = append(rules, v.Rule+":"+v.CardId)
+	}
+	return rules
+}
+
+func TestValidateConstructed(t *testing.T) {
+	legal := map[string]string{"vintage": "legal", "legacy": "legal"}
+	mountain := brew.Card{Id: "mountain", Name: "Mountain", Supertypes: []string{"basic"},
+		Types: []string{"land"}, FormatMap: legal}
+	rats := legalCard("relentless-rats", legal)
+	rats.Text = "A deck can have any number of cards named Relentless Rats."
+
+	deck := []brew.DeckCard{
+		{Quantity: 42, Board: brew.BoardMain, Card: mountain},
+		{Quantity: 10, Board: brew.BoardMain, Card: rats},
+		{Quantity: 4, Board: brew.BoardMain, Card: legalCard("bolt", legal)},
+		{Quantity: 1, Board: brew.BoardSide, Card: legalCard("bolt", legal)},
+		{Quantity: 2, Board: brew.BoardMain, Card: legalCard("time-walk", map[string]string{"vintage": "restricted", "legacy": "banned"})},
+		{Quantity: 1, Board: brew.BoardMain, Card: legalCard("shahrazad", map[string]string{"vintage": "banned"})},
+		{Quantity: 1, Board: brew.BoardMain, Card: legalCard("chaos-orb", map[string]string{})},
+		{Quantity: 16, Board: brew.BoardSide, Card: mountain},
+	}
+
+	rules := violationRules(ValidateDeck("vintage", deck))
+	expected := []string{
+		"copy_limit:bolt",
+		"restricted:time-walk",
+		"banned:shahrazad",
+		"not_legal:chaos-orb",
+		"sideboard_size:",
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %v not %v", expected, rules)
+	}
+
+	rules = violationRules(ValidateDeck("legacy", deck[:3]))
+	expected = []string{"deck_size:"}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %v not %v", expected, rules)
+	}
+}
+
+func TestValidateCommander(t *testing.T) {
+	legal := map[string]string{"commander": "legal"}
+	omnath := brew.Card{Id: "omnath", Name: "Omnath", Supertypes: []string{"legendary"},
+		Types: []string{"creature"}, ColorIdentity: []string{"green"}, FormatMap: legal}
+	forest := brew.Card{Id: "forest", Name: "Forest", Supertypes: []string{"basic"},
+		Types: []string{"land"}, FormatMap: legal}
+	elves := legalCard("llanowar-elves", legal)
+	elves.ColorIdentity = []string{"green"}
+	bolt := legalCard("bolt", legal)
+	bolt.ColorIdentity = []string{"red"}
+
+	deck := []brew.DeckCard{
+		{Quantity: 1, Board: brew.BoardCommander, Card: omnath},
+		{Quantity: 96, Board: brew.BoardMain, Card: forest},
+		{Quantity: 2, Board: brew.BoardMain, Card: elves},
+		{Quantity: 1, Board: brew.BoardMain, Card: bolt},
+	}
+	rules := violationRules(ValidateDeck("commander", deck))
+	expected := []string{"singleton:llanowar-elves", "color_identity:bolt"}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %v not %v", expected, rules)
+	}
+
+	rules = violationRules(ValidateDeck("commander", deck[1:3]))
+	expected = []string{"singleton:llanowar-elves", "commander:", "deck_size:"}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %v not %v", expected, rules)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/opentracing/opentracing-go"
 
@@ -17,12 +18,13 @@ import (
 
 func Headers(next goji.Handler) goji.Handler {
 	mw := func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/mtg/cards/random" {
+		// Random cards and saved decks change between requests
+		if r.URL.Path != "/mtg/cards/random" && !strings.HasPrefix(r.URL.Path, "/mtg/decks") {
 			w.Header().Set("Cache-Control", "public,max-age=3600")
 		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Expose-Headers", "link,content-length")
+		w.Header().Set("Access-Control-Expose-Headers", "link,content-length,x-total-count")
 		w.Header().Set("License", "The textual information presented through this API about Magic: The Gathering is copyrighted by Wizards of the Coast.")
 		w.Header().Set("Disclaimer", "This API is not produced, endorsed, supported, or affiliated with Wizards of the Coast.")
 		w.Header().Set("Pricing", "store.tcgplayer.com allows you to buy cards from any of our vendors, all at the same time, in a simple checkout experience. Shop, Compare & Save with TCGplayer.com!")
@@ -0,0 +1,3 @@
+ALTER TABLE cards ADD COLUMN color_identity varchar(5)[] DEFAULT '{}';
+
+CREATE INDEX cards_color_identity_index ON cards USING GIN(color_identity);
@@ -0,0 +1,4 @@
+ALTER TABLE cards ADD COLUMN released date;
+
+CREATE INDEX cards_released_index ON cards(released);
+CREATE INDEX cards_cmc_index ON cards(cmc);
@@ -0,0 +1,3 @@
+ALTER TABLE cards ADD COLUMN search tsvector DEFAULT ''::tsvector;
+
+CREATE INDEX cards_search_index ON cards USING GIN(search);
@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent isn't marked immutable, which is required to use it in an index
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text AS $$
+  SELECT public.unaccent('public.unaccent', $1)
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX cards_names_unaccent_index ON cards USING GIN(immutable_unaccent(lower(name)) gin_trgm_ops);
@@ -0,0 +1,7 @@
+CREATE TABLE rulings (
+        card_id           varchar(150)   NOT NULL,
+        date              date,
+        text              text           DEFAULT ''
+);
+
+CREATE INDEX rulings_card_id_index ON rulings(card_id);
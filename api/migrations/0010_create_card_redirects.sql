@@ -0,0 +1,4 @@
+CREATE TABLE card_redirects (
+        id                varchar(150)   primary key,
+        card_id           varchar(150)   NOT NULL
+);
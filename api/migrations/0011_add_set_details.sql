@@ -0,0 +1,6 @@
+ALTER TABLE sets ADD COLUMN released date;
+ALTER TABLE sets ADD COLUMN block varchar(100) DEFAULT '';
+ALTER TABLE sets ADD COLUMN size integer DEFAULT 0;
+
+CREATE INDEX sets_released_index ON sets(released);
+CREATE INDEX sets_block_index ON sets(lower(block));
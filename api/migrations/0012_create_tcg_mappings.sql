@@ -0,0 +1,88 @@
+CREATE TABLE tcg_names (
+        multiverse_id     integer        PRIMARY KEY,
+        name              varchar(200)   NOT NULL
+);
+
+CREATE TABLE tcg_sets (
+        set_id            varchar(10)    PRIMARY KEY,
+        name              varchar(200)   NOT NULL
+);
+
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1071, 'Mishra''s Factory (Fall)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1072, 'Mishra''s Factory (Spring)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1073, 'Mishra''s Factory (Summer)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1074, 'Mishra''s Factory (Winter)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1076, 'Strip Mine (Even Horizon)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1077, 'Strip Mine (Uneven Horizon)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1078, 'Strip Mine (No Horizon)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1079, 'Strip Mine (Tower)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1080, 'Urza''s Mine (Clawed Sphere)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2888, 'Urza''s Mine (Clawed Sphere)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1081, 'Urza''s Mine (Mouth)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2889, 'Urza''s Mine (Mouth)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1082, 'Urza''s Mine (Pulley)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2890, 'Urza''s Mine (Pulley)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1083, 'Urza''s Mine (Tower)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2891, 'Urza''s Mine (Tower)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1084, 'Urza''s Power Plant (Bug)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2892, 'Urza''s Power Plant (Bug)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1085, 'Urza''s Power Plant (Columns)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2893, 'Urza''s Power Plant (Columns)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1086, 'Urza''s Power Plant (Sphere)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2894, 'Urza''s Power Plant (Sphere)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1087, 'Urza''s Power Plant (Rock in Pot)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2895, 'Urza''s Power Plant (Rock in Pot)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1088, 'Urza''s Tower (Forest)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2896, 'Urza''s Tower (Forest)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1089, 'Urza''s Tower (Mountains)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2897, 'Urza''s Tower (Mountains)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2898, 'Urza''s Tower (Plains)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1090, 'Urza''s Tower (Plains)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (1091, 'Urza''s Tower (Shore)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2899, 'Urza''s Tower (Shore)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2969, 'Hungry Mist [Version 1]');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (2970, 'Hungry Mist [Version 2]');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (3021, 'Mesa Falcon [Version 1]');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (3022, 'Mesa Falcon [Version 2]');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (3207, 'Reinforcements (Version 2)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (4979, 'Pegasus Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (5472, 'Soldier Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (5503, 'Goblin Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (5560, 'Sheep Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (5601, 'Zombie Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (5607, 'Squirrel Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (9757, 'The Ultimate Nightmare of Wizards of the Coast Cu');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (9780, 'B.F.M. (Big Furry Monster Left)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (9844, 'B.F.M. (Big Furry Monster Right)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (74237, 'Our Market Research...');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (78968, 'Brothers Yamazaki (160a Sword)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (85106, 'Brothers Yamazaki (160b Pike)');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (209163, 'Hornet Token');
+INSERT INTO tcg_names (multiverse_id, name) VALUES (386322, 'Goblin Token');
+
+INSERT INTO tcg_sets (set_id, name) VALUES ('10E', '10th Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('9ED', '9th Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('8ED', '8th Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('7ED', '7th Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M15', 'Magic 2015 (M15)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M14', 'Magic 2014 (M14)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M13', 'Magic 2013 (M13)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M12', 'Magic 2012 (M12)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M11', 'Magic 2011 (M11)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('M10', 'Magic 2010 (M10)');
+INSERT INTO tcg_sets (set_id, name) VALUES ('CMD', 'Commander');
+INSERT INTO tcg_sets (set_id, name) VALUES ('HHO', 'Special Occasion');
+INSERT INTO tcg_sets (set_id, name) VALUES ('RAV', 'Ravnica');
+INSERT INTO tcg_sets (set_id, name) VALUES ('DDG', 'Duel Decks: Knights vs Dragons');
+INSERT INTO tcg_sets (set_id, name) VALUES ('DDL', 'Duel Decks: Heroes vs. Monsters');
+INSERT INTO tcg_sets (set_id, name) VALUES ('PC2', 'Planechase 2012');
+INSERT INTO tcg_sets (set_id, name) VALUES ('C13', 'Commander 2013');
+INSERT INTO tcg_sets (set_id, name) VALUES ('C14', 'Commander 2014');
+INSERT INTO tcg_sets (set_id, name) VALUES ('PD2', 'Premium Deck Series: Fire and Lightning');
+INSERT INTO tcg_sets (set_id, name) VALUES ('LEB', 'Beta Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('LEA', 'Alpha Edition');
+INSERT INTO tcg_sets (set_id, name) VALUES ('TSB', 'Timeshifted');
+INSERT INTO tcg_sets (set_id, name) VALUES ('MD1', 'Magic Modern Event Deck');
+INSERT INTO tcg_sets (set_id, name) VALUES ('CNS', 'Conspiracy');
+INSERT INTO tcg_sets (set_id, name) VALUES ('DKM', 'Deckmasters Garfield vs. Finkel');
+INSERT INTO tcg_sets (set_id, name) VALUES ('KTK', 'Khans of Tarkir');
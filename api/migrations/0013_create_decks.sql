@@ -0,0 +1,18 @@
+CREATE TABLE decks (
+        id                varchar(32)    PRIMARY KEY,
+        name              varchar(200)   NOT NULL,
+        format            varchar(20)    DEFAULT '',
+        created           timestamp      DEFAULT now(),
+        updated           timestamp      DEFAULT now()
+);
+
+CREATE TABLE deck_cards (
+        deck_id           varchar(32)    NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+        position          integer        NOT NULL,
+        board             varchar(20)    NOT NULL,
+        card_id           varchar(200)   NOT NULL,
+        multiverse_id     integer        DEFAULT 0,
+        quantity          integer        NOT NULL
+);
+
+CREATE INDEX deck_cards_deck_id_index ON deck_cards(deck_id);
@@ -11,6 +11,7 @@ type MTGSet struct {
 	Name     string    `json:"name"`
 	Code     string    `json:"code"`
 	Released string    `json:"releaseDate"`
+	Block    string    `json:"block"`
 	Border   string    `json:"border"`
 	Type     string    `json:"type"`
 	Cards    []MTGCard `json:"cards"`
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+const (
+	handSize     = 7
+	maxOddsTurn  = 20
+	maxOddsHands = 100
+)
+
+// An OddsRequest asks how likely a deck is to draw its lands and some of its
+// cards. Turn defaults to 1. Seed is a pointer so that a seed of zero can be
+// given; only a missing seed is replaced with the current time.
+type OddsRequest struct {
+	Decklist string   `json:"decklist"`
+	Cards    []string `json:"cards"`
+	Turn     int      `json:"turn"`
+	Hands    int      `json:"hands"`
+	Seed     *int64   `json:"seed"`
+}
+
+// LandOdds is the chance of at least Lands lands in the opening hand
+type LandOdds struct {
+	Lands       int     `json:"lands"`
+	Probability float64 `json:"probability"`
+}
+
+// DrawOdds is the chance of seeing at least one copy of a card by a turn,
+// going first or second
+type DrawOdds struct {
+	Name      string  `json:"name"`
+	Copies    int     `json:"copies"`
+	Turn      int     `json:"turn"`
+	OnThePlay float64 `json:"on_the_play"`
+	OnTheDraw float64 `json:"on_the_draw"`
+}
+
+type SampleHand struct {
+	Cards []string `json:"cards"`
+	Lands int      `json:"lands"`
+}
+
+type OddsResult struct {
+	Cards        int          `json:"cards"`
+	Lands        int          `json:"lands"`
+	OpeningLands []LandOdds   `json:"opening_hand_lands"`
+	Draws        []DrawOdds   `json:"draws"`
+	Seed         int64        `json:"seed"`
+	Hands        []SampleHand `json:"hands"`
+	Errors       []DeckError  `json:"errors"`
+}
+
+// choose returns the binomial coefficient n choose k
+func choose(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+	return c
+}
+
+// AtLeast returns the hypergeometric probability of drawing at least k of
+// the successes when drawing draws cards from a deck of size cards
+func AtLeast(k, successes, draws, size int) float64 {
+	if draws > size {
+		draws = size
+	}
+	total := choose(size, draws)
+	if total == 0 {
+		return 0
+	}
+	p := 0.0
+	for i := k; i <= draws && i <= successes; i++ {
+		p += choose(successes, i) * choose(size-successes, draws-i) / total
+	}
+	return math.Min(1, p)
+}
+
+func roundOdds(p float64) float64 {
+	return math.Floor(p*10000+0.5) / 10000
+}
+
+// library returns a copy of each main deck card. Commanders start in the
+// command zone and sideboards aren't drawn from.
+func library(deck []brew.DeckCard) []*brew.Card {
+	cards := []*brew.Card{}
+	for i := range deck {
+		if deck[i].Board != brew.BoardMain {
+			continue
+		}
+		for n := 0; n < deck[i].Quantity; n++ {
+			cards = append(cards, &deck[i].Card)
+		}
+	}
+	return cards
+}
+
+// OpeningLands returns the chance of at least each number of lands in the
+// opening hand
+func OpeningLands(deck []brew.DeckCard) []LandOdds {
+	cards := library(deck)
+	lands := 0
+	for _, c := range cards {
+		if hasType(c.Types, "land") {
+			lands += 1
+		}
+	}
+	odds := []LandOdds{}
+	for n := 0; n <= handSize; n++ {
+		odds = append(odds, LandOdds{Lands: n, Probability: roundOdds(AtLeast(n, lands, handSize, len(cards)))})
+	}
+	return odds
+}
+
+// DrawChance returns the chance of drawing the named card by a turn. The
+// player going first skips their first draw. The boolean is false if the
+// card isn't in the main deck.
+func DrawChance(deck []brew.DeckCard, name string, turn int) (DrawOdds, bool) {
+	cards := library(deck)
+	id := brew.CardIdentifier{Name: name}
+	odds := DrawOdds{Name: name, Turn: turn}
+	for _, c := range cards {
+		if id.Matches(c) {
+			odds.Name = c.Name
+			odds.Copies += 1
+		}
+	}
+	if odds.Copies == 0 {
+		return odds, false
+	}
+	odds.OnThePlay = roundOdds(AtLeast(1, odds.Copies, handSize+turn-1, len(cards)))
+	odds.OnTheDraw = roundOdds(AtLeast(1, odds.Copies, handSize+turn, len(cards)))
+	return odds, true
+}
+
+// SampleHands shuffles the main deck and deals opening hands. The same seed
+// always deals the same hands.
+func SampleHands(deck []brew.DeckCard, count int, seed int64) []SampleHand {
+	cards := library(deck)
+	rng := rand.New(rand.NewSource(seed))
+	hands := []SampleHand{}
+	for i := 0; i < count; i++ {
+		hand := SampleHand{Cards: []string{}}
+		for n, j := range rng.Perm(len(cards)) {
+			if n == handSize {
+				break
+			}
+			hand.Cards = append(hand.Cards, cards[j].Name)
+			if hasType(cards[j].Types, "land") {
+				hand.Lands += 1
+			}
+		}
+		hands = append(hands, hand)
+	}
+	return hands
+}
+
+func validateOddsRequest(req OddsRequest) []string {
+	errors := []string{}
+	if req.Turn < 1 || req.Turn > maxOddsTurn {
+		errors = append(errors, fmt.Sprintf("The turn must be between 1 and %d", maxOddsTurn))
+	}
+	if req.Hands < 0 || req.Hands > maxOddsHands {
+		errors = append(errors, fmt.Sprintf("The number of hands must be between 0 and %d", maxOddsHands))
+	}
+	return errors
+}
+
+func (a *API) HandleDeckOdds(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	req := OddsRequest{Turn: 1}
+	if err := readDeckRequest(w, r, &req); err != nil || strings.TrimSpace(req.Decklist) == "" {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a decklist"))
+		return
+	}
+	if errors := validateOddsRequest(req); len(errors) > 0 {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	deck, deckErrors, err := a.resolveDecklist(ctx, req.Decklist)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return
+	}
+
+	result := OddsResult{
+		OpeningLands: OpeningLands(deck),
+		Draws:        []DrawOdds{},
+		Seed:         seed,
+		Hands:        SampleHands(deck, req.Hands, seed),
+		Errors:       deckErrors,
+	}
+	for _, c := range library(deck) {
+		result.Cards += 1
+		if hasType(c.Types, "land") {
+			result.Lands += 1
+		}
+	}
+
+	errors := []string{}
+	for _, name := range req.Cards {
+		odds, ok := DrawChance(deck, name, req.Turn)
+		if !ok {
+			errors = append(errors, fmt.Sprintf("The card '%s' is not in the main deck", name))
+			continue
+		}
+		result.Draws = append(result.Draws, odds)
+	}
+	if len(errors) > 0 {
+		JSON(w, http.StatusBadRequest, Errors(errors...))
+		return
+	}
+	JSON(w, http.StatusOK, result)
+}
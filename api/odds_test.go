@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func oddsDeck() []brew.DeckCard {
+	mountain := brew.Card{Name: "Mountain", Types: []string{"land"}}
+	bolt := brew.Card{Name: "Lightning Bolt", Types: []string{"instant"}}
+	bears := brew.Card{Name: "Grizzly Bears", Types: []string{"creature"}}
+	return []brew.DeckCard{
+		{Quantity: 1, Board: brew.BoardCommander, Card: bears},
+		{Quantity: 24, Board: brew.BoardMain, Card: mountain},
+		{Quantity: 4, Board: brew.BoardMain, Card: bolt},
+		{Quantity: 32, Board: brew.BoardMain, Card: bears},
+		{Quantity: 3, Board: brew.BoardSide, Card: bolt},
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	for _, test := range []struct {
+		k, successes, draws, size int
+		expected                  float64
+	}{
+		{0, 4, 7, 60, 1},
+		{1, 4, 7, 60, 0.3995},
+		{1, 17, 7, 40, 0.9869},
+		{3, 17, 7, 40, 0.6493},
+		{5, 4, 7, 60, 0},
+		{1, 1, 10, 5, 1},
+	} {
+		p := roundOdds(AtLeast(test.k, test.successes, test.draws, test.size))
+		if p != test.expected {
+			t.Errorf("AtLeast(%d, %d, %d, %d): expected %v not %v",
+				test.k, test.successes, test.draws, test.size, test.expected, p)
+		}
+	}
+}
+
+func TestOpeningLands(t *testing.T) {
+	odds := OpeningLands(oddsDeck())
+	expected := []float64{1, 0.9784, 0.8573, 0.5879, 0.2792, 0.0828, 0.0134, 0.0009}
+	if len(odds) != len(expected) {
+		t.Fatalf("Expected %d results not %d", len(expected), len(odds))
+	}
+	for n, o := range odds {
+		if o.Lands != n || o.Probability != expected[n] {
+			t.Errorf("Expected %d lands at %v not %+v", n, expected[n], o)
+		}
+	}
+}
+
+func TestDrawChance(t *testing.T) {
+	odds, ok := DrawChance(oddsDeck(), "lightning bolt", 3)
+	if !ok {
+		t.Fatal("Expected Lightning Bolt to be in the deck")
+	}
+	expected := DrawOdds{Name: "Lightning Bolt", Copies: 4, Turn: 3, OnThePlay: 0.4875, OnTheDraw: 0.5277}
+	if odds != expected {
+		t.Errorf("Expected %+v not %+v", expected, odds)
+	}
+
+	if _, ok := DrawChance(oddsDeck(), "Shock", 3); ok {
+		t.Error("Expected Shock not to be in the deck")
+	}
+}
+
+func TestSampleHands(t *testing.T) {
+	hands := SampleHands(oddsDeck(), 5, 42)
+	if len(hands) != 5 {
+		t.Fatalf("Expected 5 hands not %d", len(hands))
+	}
+	for _, hand := range hands {
+		if len(hand.Cards) != 7 {
+			t.Errorf("Expected 7 cards not %d", len(hand.Cards))
+		}
+		lands := 0
+		for _, name := range hand.Cards {
+			if name == "Mountain" {
+				lands += 1
+			}
+		}
+		if lands != hand.Lands {
+			t.Errorf("Expected %d lands not %d", lands, hand.Lands)
+		}
+	}
+	if again := SampleHands(oddsDeck(), 5, 42); !reflect.DeepEqual(hands, again) {
+		t.Error("Expected the same seed to deal the same hands")
+	}
+	if other := SampleHands(oddsDeck(), 5, 7); reflect.DeepEqual(hands, other) {
+		t.Error("Expected a different seed to deal different hands")
+	}
+}
+
+func TestValidateOddsRequest(t *testing.T) {
+	if errors := validateOddsRequest(OddsRequest{Turn: 1, Hands: 10}); len(errors) != 0 {
+		t.Errorf("Expected no errors, not %v", errors)
+	}
+	if errors := validateOddsRequest(OddsRequest{Turn: 0, Hands: 1000}); len(errors) != 2 {
+		t.Errorf("Expected two errors, not %v", errors)
+	}
+}
+
+// collectionReader finds cards by name without a database
+type collectionReader struct {
+	brew.Reader
+	cards []brew.Card
+}
+
+func (c collectionReader) GetCardCollection(ctx context.Context, ids []brew.CardIdentifier) ([]brew.Card, []brew.CardIdentifier, error) {
+	return c.cards, []brew.CardIdentifier{}, nil
+}
+
+func TestHandleDeckOddsSeed(t *testing.T) {
+	mountain := brew.Card{Id: "mountain", Name: "Mountain", Types: []string{"land"}}
+	api := &API{c: collectionReader{cards: []brew.Card{mountain}}}
+
+	odds := func(body string) OddsResult {
+		r, _ := http.NewRequest("POST", "/mtg/decks/odds", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		api.HandleDeckOdds(context.Background(), w, r)
+		var result OddsResult
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 not %d: %s", w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// Zero is a seed like any other
+	if result := odds(`{"decklist": "20 Mountain", "seed": 0}`); result.Seed != 0 {
+		t.Errorf("Expected a seed of 0, not %d", result.Seed)
+	}
+	if result := odds(`{"decklist": "20 Mountain", "seed": 42}`); result.Seed != 42 {
+		t.Errorf("Expected a seed of 42, not %d", result.Seed)
+	}
+	if result := odds(`{"decklist": "20 Mountain"}`); result.Seed == 0 {
+		t.Error("Expected a random seed")
+	}
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"stackmachine.com/cql"
+
+	"github.com/kyleconroy/deckbrew/brew"
+	"github.com/kyleconroy/deckbrew/config"
+)
+
+// A PriceRow is a single line of a price guide. Prices are in cents.
+type PriceRow struct {
+	Line   int
+	Set    string
+	Name   string
+	Foil   bool
+	Low    int
+	Median int
+	High   int
+}
+
+// A PriceSnapshot is the price of a single edition
+type PriceSnapshot struct {
+	MultiverseId int
+	Foil         bool
+	Low          int
+	Median       int
+	High         int
+}
+
+// parseCents turns a dollar amount such as "$1.25" into cents. Empty values
+// are zero.
+func parseCents(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "$")
+	value = strings.Replace(value, ",", "", -1)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("The price '%s' is not a dollar amount", value)
+	}
+	return dollarsToCents(f)
+}
+
+// dollarsToCents rejects prices that can't be stored as a number of cents
+func dollarsToCents(f float64) (int, error) {
+	if f < 0 || math.IsNaN(f) || math.IsInf(f, 0) || f > math.MaxInt32/100 {
+		return 0, fmt.Errorf("The price '%v' is not a dollar amount", f)
+	}
+	return int(f*100 + 0.5), nil
+}
+
+func parseFoil(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "1", "foil":
+		return true
+	}
+	return false
+}
+
+// ParsePriceCSV reads a price guide with a header row. The set, name, low,
+// median (or mid) and high columns are required and foil is optional.
+func ParsePriceCSV(r io.Reader) ([]PriceRow, error) {
+	rows := []PriceRow{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return rows, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "mid" {
+			name = "median"
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"set", "name", "low", "median", "high"} {
+		if _, ok := columns[name]; !ok {
+			return rows, fmt.Errorf("The price guide is missing the '%s' column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line += 1
+		if err != nil {
+			return rows, err
+		}
+		row := PriceRow{
+			Line: line,
+			Set:  field(record, "set"),
+			Name: field(record, "name"),
+			Foil: parseFoil(field(record, "foil")),
+		}
+		for _, price := range []struct {
+			column string
+			cents  *int
+		}{
+			{"low", &row.Low},
+			{"median", &row.Median},
+			{"high", &row.High},
+		} {
+			*price.cents, err = parseCents(field(record, price.column))
+			if err != nil {
+				return rows, fmt.Errorf("line %d: %s", line, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ParsePriceJSON reads a price guide stored as a list of objects with the
+// same fields as the CSV format. Prices are numbers in dollars.
+func ParsePriceJSON(r io.Reader) ([]PriceRow, error) {
+	rows := []PriceRow{}
+	var entries []struct {
+		Set    string  `json:"set"`
+		Name   string  `json:"name"`
+		Foil   bool    `json:"foil"`
+		Low    float64 `json:"low"`
+		Median float64 `json:"median"`
+		High   float64 `json:"high"`
+	}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return rows, err
+	}
+	for i, e := range entries {
+		row := PriceRow{Line: i + 1, Set: e.Set, Name: e.Name, Foil: e.Foil}
+		for _, price := range []struct {
+			dollars float64
+			cents   *int
+		}{
+			{e.Low, &row.Low},
+			{e.Median, &row.Median},
+			{e.High, &row.High},
+		} {
+			cents, err := dollarsToCents(price.dollars)
+			if err != nil {
+				return rows, fmt.Errorf("row %d: %s", row.Line, err)
+			}
+			*price.cents = cents
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func priceKey(set, name string) string {
+	return brew.NormalizeName(set) + "|" + brew.NormalizeName(name)
+}
+
+// PriceIndex maps a price guide set and card name to multiverse ids
+type PriceIndex map[string][]int
+
+// NewPriceIndex indexes every edition of a priced set using the same set and
+// card names as TCGplayer
+func NewPriceIndex(tcg brew.TCGMap, sets []brew.Set, cards []brew.Card) PriceIndex {
+	guides := map[string]string{}
+	for _, s := range sets {
+		if !s.Priced {
+			continue
+		}
+		guides[s.Id] = s.PriceGuide
+		if s.PriceGuide == "" {
+			guides[s.Id] = tcg.Set(s.Id, s.Name)
+		}
+	}
+
+	index := PriceIndex{}
+	for _, c := range cards {
+		for _, e := range c.Editions {
+			guide, ok := guides[e.SetId]
+			if !ok || e.MultiverseId == 0 {
+				continue
+			}
+			key := priceKey(guide, tcg.Name(c.Name, e.MultiverseId))
+			index[key] = append(index[key], e.MultiverseId)
+		}
+	}
+	return index
+}
+
+// Match returns a snapshot for every edition the rows refer to, along with
+// the rows that don't match any edition
+func (index PriceIndex) Match(rows []PriceRow) ([]PriceSnapshot, []PriceRow) {
+	snapshots := []PriceSnapshot{}
+	unmatched := []PriceRow{}
+	for _, row := range rows {
+		ids, ok := index[priceKey(row.Set, row.Name)]
+		if !ok {
+			unmatched = append(unmatched, row)
+			continue
+		}
+		for _, id := range ids {
+			snapshots = append(snapshots, PriceSnapshot{
+				MultiverseId: id,
+				Foil:         row.Foil,
+				Low:          row.Low,
+				Median:       row.Median,
+				High:         row.High,
+			})
+		}
+	}
+	return snapshots, unmatched
+}
+
+func fetchCards(ctx context.Context, db *cql.DB) ([]brew.Card, error) {
+	cards := []brew.Card{}
+
+	rows, err := db.QueryC(ctx, "SELECT record FROM cards")
+	if err != nil {
+		return cards, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var blob []byte
+		var card brew.Card
+		if err := rows.Scan(&blob); err != nil {
+			return cards, err
+		}
+		if err := json.Unmarshal(blob, &card); err != nil {
+			return cards, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+const queryInsertPrice = `
+INSERT INTO prices (multiverse_id, created, foil, low, median, high)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// CreatePrices writes every snapshot in a single transaction using the same
+// creation time
+func CreatePrices(db *cql.DB, snapshots []PriceSnapshot, created time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, p := range snapshots {
+		_, err := tx.Exec(queryInsertPrice, strconv.Itoa(p.MultiverseId), created,
+			p.Foil, p.Low, p.Median, p.High)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error inserting price %+v %s", p, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ImportPrices loads a CSV or JSON price guide into the prices table
+func ImportPrices(path string) error {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var rows []PriceRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = ParsePriceCSV(f)
+	case ".json":
+		rows, err = ParsePriceJSON(f)
+	default:
+		return fmt.Errorf("price guides must be .csv or .json files, not %s", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, err := brew.NewReader(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+	sets, err := client.GetSets(ctx, brew.SetSearch{})
+	if err != nil {
+		return err
+	}
+	cards, err := fetchCards(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	tcg, err := brew.LoadTCGMap(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+
+	snapshots, unmatched := NewPriceIndex(tcg, sets, cards).Match(rows)
+	for _, row := range unmatched {
+		log.Printf("unmatched line %d: %s / %s", row.Line, row.Set, row.Name)
+	}
+
+	if err := CreatePrices(cfg.DB, snapshots, time.Now().UTC()); err != nil {
+		return err
+	}
+	log.Printf("imported %d prices from %d rows, %d rows unmatched",
+		len(snapshots), len(rows), len(unmatched))
+	return nil
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestParseCents(t *testing.T) {
+	for value, expected := range map[string]int{
+		"":         0,
+		"0.25":     25,
+		"$1.99":    199,
+		"1,024.50": 102450,
+		" 3 ":      300,
+	} {
+		cents, err := parseCents(value)
+		if err != nil {
+			t.Errorf("%s: %s", value, err)
+		}
+		if cents != expected {
+			t.Errorf("%s: expected %d not %d", value, expected, cents)
+		}
+	}
+	if _, err := parseCents("free"); err == nil {
+		t.Errorf("Expected 'free' to return an error")
+	}
+}
+
+func TestParsePriceCSV(t *testing.T) {
+	guide := "Set,Name,Low,Mid,High,Foil\n" +
+		"Urza's Legacy,About Face,$0.10,$0.25,$0.99,\n" +
+		"Urza's Legacy,About Face,1.00,2.00,5.00,yes\n"
+	rows, err := ParsePriceCSV(strings.NewReader(guide))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PriceRow{
+		{Line: 2, Set: "Urza's Legacy", Name: "About Face", Low: 10, Median: 25, High: 99},
+		{Line: 3, Set: "Urza's Legacy", Name: "About Face", Foil: true, Low: 100, Median: 200, High: 500},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %+v not %+v", expected, rows)
+	}
+
+	if _, err := ParsePriceCSV(strings.NewReader("set,name,low\n")); err == nil {
+		t.Errorf("Expected a missing column to return an error")
+	}
+}
+
+func TestParsePriceJSON(t *testing.T) {
+	guide := `[{"set": "Urza's Legacy", "name": "About Face", "low": 0.1, "median": 0.25, "high": 0.99}]`
+	rows, err := ParsePriceJSON(strings.NewReader(guide))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PriceRow{
+		{Line: 1, Set: "Urza's Legacy", Name: "About Face", Low: 10, Median: 25, High: 99},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %+v not %+v", expected, rows)
+	}
+}
+
+func TestParsePriceJSONErrors(t *testing.T) {
+	guide := `[
+  {"set": "Urza's Legacy", "name": "About Face", "low": 0.1, "median": 0.25, "high": 0.99},
+  {"set": "Urza's Legacy", "name": "Strip Mine", "low": -1, "median": 2, "high": 3}
+]`
+	_, err := ParsePriceJSON(strings.NewReader(guide))
+	if err == nil {
+		t.Fatal("Expected a negative price to return an error")
+	}
+	if !strings.HasPrefix(err.Error(), "row 2:") {
+		t.Errorf("Expected the error to name row 2, not %s", err)
+	}
+
+	if _, err := ParsePriceJSON(strings.NewReader(`[{"set": "a", "name": "b", "high": 1e300}]`)); err == nil {
+		t.Error("Expected a price too large for cents to return an error")
+	}
+}
+
+func TestPriceIndexMatch(t *testing.T) {
+	sets := []brew.Set{
+		{Id: "ULG", Name: "Urza's Legacy", Priced: true},
+		{Id: "M15", Name: "Magic 2015 Core Set", PriceGuide: "Magic 2015 (M15)", Priced: true},
+		{Id: "MED", Name: "Masters Edition", Priced: false},
+	}
+	cards := []brew.Card{
+		{Name: "About Face", Editions: []brew.Edition{{SetId: "ULG", MultiverseId: 12414}}},
+		{Name: "Jace, the Living Guildpact", Editions: []brew.Edition{{SetId: "M15", MultiverseId: 383287}}},
+		{Name: "Lightning Bolt", Editions: []brew.Edition{{SetId: "MED", MultiverseId: 159185}}},
+		{Name: "Strip Mine", Editions: []brew.Edition{{SetId: "ULG", MultiverseId: 1076}}},
+	}
+	rows := []PriceRow{
+		{Line: 2, Set: "urza's legacy", Name: "ABOUT FACE", Median: 25},
+		{Line: 3, Set: "Magic 2015 (M15)", Name: "Jace, the Living Guildpact", Median: 1000},
+		{Line: 4, Set: "Masters Edition", Name: "Lightning Bolt", Median: 100},
+		{Line: 5, Set: "Urza's Legacy", Name: "Strip Mine (Even Horizon)", Median: 300},
+	}
+
+	tcg := brew.TCGMap{
+		Sets:  map[string]string{},
+		Names: map[int]string{1076: "Strip Mine (Even Horizon)"},
+	}
+	snapshots, unmatched := NewPriceIndex(tcg, sets, cards).Match(rows)
+	expected := []PriceSnapshot{
+		{MultiverseId: 12414, Median: 25},
+		{MultiverseId: 383287, Median: 1000},
+		{MultiverseId: 1076, Median: 300},
+	}
+	if !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("Expected %+v not %+v", expected, snapshots)
+	}
+	if len(unmatched) != 1 || unmatched[0].Line != 4 {
+		t.Errorf("Expected line 4 to be unmatched, not %+v", unmatched)
+	}
+}
@@ -0,0 +1,460 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// The query language is a small subset of the syntax popularized by
+// Scryfall. Terms are separated by whitespace and combined with AND, the
+// keyword "or" combines terms with OR, a leading "-" negates a term and
+// parentheses group terms together.
+//
+//     t:creature c:rg cmc>=3 o:"draw a card" -r:common (s:ktk or s:frf)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenOpen
+	tokenClose
+	tokenNot
+	tokenOr
+	tokenTerm
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	term termNode
+}
+
+// A queryNode is a node in the parsed query AST
+type queryNode interface {
+	compile() (brew.Search, error)
+}
+
+type andNode []queryNode
+
+type orNode []queryNode
+
+type notNode struct {
+	node queryNode
+}
+
+type termNode struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+func isTermEnd(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')'
+}
+
+func isOperator(r rune) bool {
+	return r == ':' || r == '=' || r == '<' || r == '>' || r == '!'
+}
+
+func lexQuery(q string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(q)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, pos: start})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, pos: start})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{kind: tokenNot, pos: start})
+			i++
+			continue
+		}
+
+		// Read the key, or the entire term if it has no operator
+		for i < len(runes) && !isTermEnd(runes[i]) && !isOperator(runes[i]) && runes[i] != '"' {
+			i++
+		}
+		key := string(runes[start:i])
+
+		if i >= len(runes) || isTermEnd(runes[i]) {
+			switch strings.ToLower(key) {
+			case "or":
+				tokens = append(tokens, token{kind: tokenOr, pos: start})
+			case "and":
+			default:
+				tokens = append(tokens, token{kind: tokenTerm, pos: start, term: termNode{Op: ":", Value: key}})
+			}
+			continue
+		}
+
+		op := ":"
+		if key != "" || runes[i] != '"' {
+			opStart := i
+			for i < len(runes) && isOperator(runes[i]) {
+				i++
+			}
+			op = string(runes[opStart:i])
+			switch op {
+			case ":", "=", "!=", "<", "<=", ">", ">=":
+			default:
+				return tokens, fmt.Errorf("Unknown operator '%s' in query at position %d", op, opStart+1)
+			}
+		}
+
+		var value string
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return tokens, fmt.Errorf("Unterminated quote in query at position %d", i+1)
+			}
+			value = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			valueStart := i
+			for i < len(runes) && !isTermEnd(runes[i]) {
+				i++
+			}
+			value = string(runes[valueStart:i])
+		}
+
+		if value == "" {
+			return tokens, fmt.Errorf("Missing value for '%s' in query at position %d", key, start+1)
+		}
+
+		tokens = append(tokens, token{kind: tokenTerm, pos: start, term: termNode{Key: key, Op: op, Value: value}})
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	nodes := []queryNode{}
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if p.peek().kind != tokenOr {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return orNode(nodes), nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	nodes := []queryNode{}
+	for {
+		switch p.peek().kind {
+		case tokenEOF, tokenClose, tokenOr:
+			if len(nodes) == 0 {
+				return nil, fmt.Errorf("Expected a search term in query at position %d", p.peek().pos+1)
+			}
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return andNode(nodes), nil
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNot:
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, fmt.Errorf("Missing ')' in query at position %d", closing.pos+1)
+		}
+		return node, nil
+	case tokenTerm:
+		return t.term, nil
+	}
+	return nil, fmt.Errorf("Expected a search term in query at position %d", t.pos+1)
+}
+
+// parseQueryString turns a query into an AST
+func parseQueryString(q string) (queryNode, error) {
+	tokens, err := lexQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	p := queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("Unexpected ')' in query at position %d", t.pos+1)
+	}
+	return node, nil
+}
+
+// CompileQuery parses a query and compiles it into a search
+func CompileQuery(q string) (brew.Search, error) {
+	node, err := parseQueryString(q)
+	if err != nil {
+		return brew.Search{}, err
+	}
+	return node.compile()
+}
+
+func (n andNode) compile() (brew.Search, error) {
+	s := brew.Search{}
+	for _, node := range n {
+		sub, err := node.compile()
+		if err != nil {
+			return s, err
+		}
+		s.And = append(s.And, sub)
+	}
+	return s, nil
+}
+
+func (n orNode) compile() (brew.Search, error) {
+	s := brew.Search{}
+	for _, node := range n {
+		sub, err := node.compile()
+		if err != nil {
+			return s, err
+		}
+		s.Or = append(s.Or, sub)
+	}
+	return s, nil
+}
+
+func (n notNode) compile() (brew.Search, error) {
+	sub, err := n.node.compile()
+	return brew.Search{Not: []brew.Search{sub}}, err
+}
+
+var queryColors = map[rune]string{
+	'w': "white",
+	'u': "blue",
+	'b': "black",
+	'r': "red",
+	'g': "green",
+}
+
+var queryRarities = map[string]string{
+	"c": "common",
+	"u": "uncommon",
+	"r": "rare",
+	"m": "mythic",
+	"s": "special",
+	"b": "basic",
+}
+
+var queryComparisons = map[string]string{
+	":":  "eq",
+	"=":  "eq",
+	">":  "gt",
+	">=": "gte",
+	"<":  "lt",
+	"<=": "lte",
+}
+
+func (t termNode) compile() (brew.Search, error) {
+	s := brew.Search{}
+	key := strings.ToLower(t.Key)
+	value := strings.ToLower(t.Value)
+
+	if t.Op == "!=" {
+		sub, err := termNode{Key: t.Key, Op: ":", Value: t.Value}.compile()
+		return brew.Search{Not: []brew.Search{sub}}, err
+	}
+
+	switch key {
+	case "cmc", "mv", "pow", "power", "tou", "toughness", "loy", "loyalty":
+		return t.compileComparison(key, value)
+	case "c", "color":
+		return t.compileColors(value)
+	case "id", "identity":
+		return t.compileIdentity(value)
+	}
+
+	if t.Op != ":" && t.Op != "=" {
+		return s, fmt.Errorf("The '%s' filter doesn't support the '%s' operator", t.Key, t.Op)
+	}
+
+	switch key {
+	case "", "n", "name":
+		pattern, err := likePattern(t.Value)
+		s.Names = []string{pattern}
+		return s, err
+	case "o", "oracle":
+		pattern, err := likePattern(t.Value)
+		s.Rules = []string{pattern}
+		return s, err
+	case "t", "type":
+		switch {
+		case validTypes[value]:
+			s.Types = []string{value}
+		case validSupertypes[value]:
+			s.Supertypes = []string{value}
+		default:
+			s.Subtypes = []string{value}
+		}
+		return s, nil
+	case "s", "set", "e", "edition":
+		s.Sets = []string{value}
+		return s, nil
+	case "r", "rarity":
+		if r, ok := queryRarities[value]; ok {
+			value = r
+		}
+		if !validRarities[value] {
+			return s, fmt.Errorf("The rarity '%s' is not recognized", t.Value)
+		}
+		s.Rarities = []string{value}
+		return s, nil
+	case "f", "format":
+		if !validFormats[value] {
+			return s, fmt.Errorf("The format '%s' is not recognized", t.Value)
+		}
+		s.Formats = []string{value}
+		return s, nil
+	}
+
+	return s, fmt.Errorf("The query key '%s' is not recognized", t.Key)
+}
+
+func (t termNode) compileComparison(key, value string) (brew.Search, error) {
+	s := brew.Search{}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return s, fmt.Errorf("The %s value '%s' must be a number", key, t.Value)
+	}
+	c := []brew.Comparison{{Op: queryComparisons[t.Op], Value: n}}
+	switch key {
+	case "cmc", "mv":
+		s.CMC = c
+	case "pow", "power":
+		s.Power = c
+	case "tou", "toughness":
+		s.Toughness = c
+	case "loy", "loyalty":
+		s.Loyalty = c
+	}
+	return s, nil
+}
+
+// Colors follow Scryfall, so c:rg and c>=rg find cards that are at least red
+// and green, c=rg finds cards that are exactly red and green and c<=rg finds
+// cards that are at most red and green.
+func (t termNode) compileColors(value string) (brew.Search, error) {
+	s := brew.Search{}
+
+	switch t.Op {
+	case ":", ">=":
+		s.ColorMatch = brew.MatchAll
+	case "=":
+		s.ColorMatch = brew.MatchExact
+	case "<=":
+		s.ColorMatch = brew.MatchSubset
+	default:
+		return s, fmt.Errorf("The '%s' filter doesn't support the '%s' operator", t.Key, t.Op)
+	}
+
+	switch {
+	case validColors[value]:
+		s.Colors = []string{value}
+		return s, nil
+	case value == "m" || value == "multicolor":
+		s.ColorMatch = ""
+		s.IncludeMulticolor = true
+		s.Multicolor = true
+		return s, nil
+	case value == "c" || value == "colorless":
+		s.ColorMatch = ""
+		s.Not = []brew.Search{{Colors: []string{"white", "blue", "black", "red", "green"}}}
+		return s, nil
+	}
+
+	colors, err := t.colorLetters(value)
+	s.Colors = colors
+	return s, err
+}
+
+// Color identity searches always find cards that fit within the given
+// colors, so id:bg finds cards that can be played in a Golgari deck.
+func (t termNode) compileIdentity(value string) (brew.Search, error) {
+	s := brew.Search{}
+	if t.Op != ":" && t.Op != "=" && t.Op != "<=" {
+		return s, fmt.Errorf("The '%s' filter doesn't support the '%s' operator", t.Key, t.Op)
+	}
+	if validColors[value] {
+		s.Identity = []string{value}
+		return s, nil
+	}
+	colors, err := t.colorLetters(value)
+	s.Identity = colors
+	return s, err
+}
+
+func (t termNode) colorLetters(value string) ([]string, error) {
+	colors := []string{}
+	for _, r := range value {
+		color, ok := queryColors[r]
+		if !ok {
+			return colors, fmt.Errorf("The color '%s' is not recognized", t.Value)
+		}
+		colors = append(colors, color)
+	}
+	return colors, nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestCompileQuery(t *testing.T) {
+	for q, expected := range map[string]brew.Search{
+		"t:creature":      brew.Search{Types: []string{"creature"}},
+		"t:legendary":     brew.Search{Supertypes: []string{"legendary"}},
+		"t:zombie":        brew.Search{Subtypes: []string{"zombie"}},
+		"c:rg":            brew.Search{Colors: []string{"red", "green"}, ColorMatch: brew.MatchAll},
+		"c=wu":            brew.Search{Colors: []string{"white", "blue"}, ColorMatch: brew.MatchExact},
+		"c<=wu":           brew.Search{Colors: []string{"white", "blue"}, ColorMatch: brew.MatchSubset},
+		"cmc>=3":          brew.Search{CMC: []brew.Comparison{{Op: "gte", Value: 3}}},
+		`o:"draw a card"`: brew.Search{Rules: []string{`"%draw a card%"`}},
+		"-r:common": brew.Search{Not: []brew.Search{
+			{Rarities: []string{"common"}},
+		}},
+		"-(s:ktk or s:frf)": brew.Search{Not: []brew.Search{
+			{Or: []brew.Search{
+				{Sets: []string{"ktk"}},
+				{Sets: []string{"frf"}},
+			}},
+		}},
+		"s:ktk or s:frf": brew.Search{Or: []brew.Search{
+			{Sets: []string{"ktk"}},
+			{Sets: []string{"frf"}},
+		}},
+		"t:goblin (r:m or r:r)": brew.Search{And: []brew.Search{
+			{Subtypes: []string{"goblin"}},
+			{Or: []brew.Search{
+				{Rarities: []string{"mythic"}},
+				{Rarities: []string{"rare"}},
+			}},
+		}},
+		"bolt":   brew.Search{Names: []string{`"%bolt%"`}},
+		"id:bg":  brew.Search{Identity: []string{"black", "green"}},
+		"pow>=5": brew.Search{Power: []brew.Comparison{{Op: "gte", Value: 5}}},
+		"tou<2":  brew.Search{Toughness: []brew.Comparison{{Op: "lt", Value: 2}}},
+	} {
+		s, err := CompileQuery(q)
+		if err != nil {
+			t.Errorf("%s: %s", q, err)
+			continue
+		}
+		if !reflect.DeepEqual(s, expected) {
+			t.Errorf("%s: expected %+v not %+v", q, expected, s)
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		"(t:creature",
+		"t:creature)",
+		"t:creature or",
+		`o:"draw`,
+		"foo:bar",
+		"c:xyz",
+		"cmc>=three",
+		"t>creature",
+		"r:legendary",
+		"()",
+	} {
+		if _, err := CompileQuery(q); err == nil {
+			t.Errorf("Expected '%s' to return an error", q)
+		}
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?q=t:creature+c:rg+cmc>%3D3+o:%22draw+a+card%22+-r:common+(s:ktk+or+s:frf)")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if len(s.And) != 1 || len(s.And[0].And) != 6 {
+		t.Errorf("Expected six grouped terms, not %+v", s.And)
+	}
+
+	u, _ = url.Parse("/mtg/cards?q=(t:creature")
+	_, err, errors = ParseSearch(u)
+	if err == nil || len(errors) != 1 {
+		t.Errorf("Expected a single query error, not %v", errors)
+	}
+}
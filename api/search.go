@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kyleconroy/deckbrew/brew"
 )
@@ -17,16 +18,24 @@ func toLower(strs []string) []string {
 	return downers
 }
 
+func likePattern(value string) (string, error) {
+	if strings.ContainsAny(value, "%_") {
+		return "", fmt.Errorf("Search string can't contain '%%' or '_'")
+	}
+	return "\"%" + strings.Replace(value, "\"", "", -1) + "%\"", nil
+}
+
 func extractPattern(args url.Values, key string) ([]string, error) {
 	items := []string{}
 	for _, oracle := range args[key] {
 		if oracle == "" {
 			continue
 		}
-		if strings.ContainsAny(oracle, "%_") {
-			return items, fmt.Errorf("Search string can't contain '%%' or '_'")
+		pattern, err := likePattern(oracle)
+		if err != nil {
+			return items, err
 		}
-		items = append(items, "\"%"+strings.Replace(oracle, "\"", "", -1)+"%\"")
+		items = append(items, pattern)
 	}
 	return items, nil
 }
@@ -43,6 +52,72 @@ func extractStrings(args url.Values, key string, allowed map[string]bool) ([]str
 	return items, nil
 }
 
+var validSupertypes = map[string]bool{
+	"legendary": true,
+	"basic":     true,
+	"world":     true,
+	"snow":      true,
+	"ongoing":   true,
+}
+
+var validColors = map[string]bool{
+	"red":   true,
+	"black": true,
+	"blue":  true,
+	"white": true,
+	"green": true,
+}
+
+var validStatus = map[string]bool{
+	"legal":      true,
+	"banned":     true,
+	"restricted": true,
+}
+
+var validFormats = map[string]bool{
+	"commander": true,
+	"standard":  true,
+	"modern":    true,
+	"vintage":   true,
+	"legacy":    true,
+}
+
+var validRarities = map[string]bool{
+	"common":   true,
+	"uncommon": true,
+	"rare":     true,
+	"mythic":   true,
+	"special":  true,
+	"basic":    true,
+}
+
+var validTypes = map[string]bool{
+	"creature":     true,
+	"land":         true,
+	"tribal":       true,
+	"phenomenon":   true,
+	"summon":       true,
+	"enchantment":  true,
+	"sorcery":      true,
+	"vanguard":     true,
+	"instant":      true,
+	"planeswalker": true,
+	"artifact":     true,
+	"plane":        true,
+	"scheme":       true,
+}
+
+var validSorts = map[string]bool{
+	"name":      true,
+	"cmc":       true,
+	"power":     true,
+	"toughness": true,
+	"rarity":    true,
+	"released":  true,
+	"price":     true,
+	"relevance": true,
+}
+
 func parseMulticolor(s *brew.Search, args url.Values) error {
 	switch args.Get("multicolor") {
 	case "true":
@@ -70,13 +145,7 @@ func parseMultiverseIDs(s *brew.Search, args url.Values) error {
 }
 
 func parseSupertypes(s *brew.Search, args url.Values) (err error) {
-	s.Supertypes, err = extractStrings(args, "supertype", map[string]bool{
-		"legendary": true,
-		"basic":     true,
-		"world":     true,
-		"snow":      true,
-		"ongoing":   true,
-	})
+	s.Supertypes, err = extractStrings(args, "supertype", validSupertypes)
 	return
 }
 
@@ -93,64 +162,32 @@ func parseSets(s *brew.Search, args url.Values) error {
 }
 
 func parseColors(s *brew.Search, args url.Values) (err error) {
-	s.Colors, err = extractStrings(args, "color", map[string]bool{
-		"red":   true,
-		"black": true,
-		"blue":  true,
-		"white": true,
-		"green": true,
-	})
+	s.Colors, err = extractStrings(args, "color", validColors)
+	return
+}
+
+func parseIdentity(s *brew.Search, args url.Values) (err error) {
+	s.Identity, err = extractStrings(args, "identity", validColors)
 	return
 }
 
 func parseStatus(s *brew.Search, args url.Values) (err error) {
-	s.Status, err = extractStrings(args, "status", map[string]bool{
-		"legal":      true,
-		"banned":     true,
-		"restricted": true,
-	})
+	s.Status, err = extractStrings(args, "status", validStatus)
 	return
 }
 
 func parseFormat(s *brew.Search, args url.Values) (err error) {
-	s.Formats, err = extractStrings(args, "format", map[string]bool{
-		"commander": true,
-		"standard":  true,
-		"modern":    true,
-		"vintage":   true,
-		"legacy":    true,
-	})
+	s.Formats, err = extractStrings(args, "format", validFormats)
 	return
 }
 
 func parseRarity(s *brew.Search, args url.Values) (err error) {
-	s.Rarities, err = extractStrings(args, "rarity", map[string]bool{
-		"common":   true,
-		"uncommon": true,
-		"rare":     true,
-		"mythic":   true,
-		"special":  true,
-		"basic":    true,
-	})
+	s.Rarities, err = extractStrings(args, "rarity", validRarities)
 	return
 }
 
 func parseTypes(s *brew.Search, args url.Values) (err error) {
-	s.Types, err = extractStrings(args, "type", map[string]bool{
-		"creature":     true,
-		"land":         true,
-		"tribal":       true,
-		"phenomenon":   true,
-		"summon":       true,
-		"enchantment":  true,
-		"sorcery":      true,
-		"vanguard":     true,
-		"instant":      true,
-		"planeswalker": true,
-		"artifact":     true,
-		"plane":        true,
-		"scheme":       true,
-	})
+	s.Types, err = extractStrings(args, "type", validTypes)
 	return
 }
 
@@ -164,9 +201,128 @@ func parseRules(s *brew.Search, args url.Values) (err error) {
 	return
 }
 
+func parseMatches(s *brew.Search, args url.Values) error {
+	for _, pair := range []struct {
+		match *brew.Match
+		key   string
+	}{
+		{&s.ColorMatch, "color_match"},
+		{&s.FormatMatch, "format_match"},
+		{&s.RarityMatch, "rarity_match"},
+		{&s.SetMatch, "set_match"},
+		{&s.SubtypeMatch, "subtype_match"},
+		{&s.SupertypeMatch, "supertype_match"},
+		{&s.TypeMatch, "type_match"},
+	} {
+		value := brew.Match(args.Get(pair.key))
+		switch value {
+		case "":
+			continue
+		case brew.MatchAny, brew.MatchAll, brew.MatchExact, brew.MatchSubset, brew.MatchNone:
+			*pair.match = value
+		default:
+			return fmt.Errorf("The %s '%s' is not recognized", pair.key, value)
+		}
+	}
+	return nil
+}
+
+// Numeric filters take an optional operator, such as cmc=gte:2. Multiple
+// values must all match, so cmc=gte:2&cmc=lte:4 finds cards between two and
+// four mana.
+func extractComparisons(args url.Values, key string) ([]brew.Comparison, error) {
+	comparisons := []brew.Comparison{}
+	for _, arg := range args[key] {
+		op, value := "eq", arg
+		if i := strings.Index(arg, ":"); i >= 0 {
+			op, value = arg[:i], arg[i+1:]
+		}
+		switch op {
+		case "eq", "gt", "gte", "lt", "lte":
+		default:
+			return comparisons, fmt.Errorf("The %s operator '%s' is not recognized", key, op)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return comparisons, fmt.Errorf("The %s value '%s' must be a number", key, value)
+		}
+		comparisons = append(comparisons, brew.Comparison{Op: op, Value: n})
+	}
+	return comparisons, nil
+}
+
+func parseCMC(s *brew.Search, args url.Values) (err error) {
+	s.CMC, err = extractComparisons(args, "cmc")
+	return
+}
+
+func parsePower(s *brew.Search, args url.Values) (err error) {
+	s.Power, err = extractComparisons(args, "power")
+	return
+}
+
+func parseToughness(s *brew.Search, args url.Values) (err error) {
+	s.Toughness, err = extractComparisons(args, "toughness")
+	return
+}
+
+func parseLoyalty(s *brew.Search, args url.Values) (err error) {
+	s.Loyalty, err = extractComparisons(args, "loyalty")
+	return
+}
+
+func parseSort(s *brew.Search, args url.Values) error {
+	sort := args.Get("sort")
+	if sort != "" && !validSorts[sort] {
+		return fmt.Errorf("The sort '%s' is not recognized", sort)
+	}
+	if sort == "relevance" && len(s.Text) == 0 {
+		return fmt.Errorf("Sorting by relevance requires a text search")
+	}
+	s.Sort = sort
+
+	switch order := args.Get("order"); order {
+	case "", "asc", "desc":
+		s.Order = order
+	default:
+		return fmt.Errorf("Order should be either 'asc' or 'desc'")
+	}
+	return nil
+}
+
+func parseQuery(s *brew.Search, args url.Values) error {
+	q := args.Get("q")
+	if strings.TrimSpace(q) == "" {
+		return nil
+	}
+	query, err := CompileQuery(q)
+	if err != nil {
+		return err
+	}
+	s.And = append(s.And, query)
+	return nil
+}
+
+func parseText(s *brew.Search, args url.Values) error {
+	for _, text := range args["text"] {
+		if strings.TrimSpace(text) != "" {
+			s.Text = append(s.Text, text)
+		}
+	}
+	return nil
+}
+
 func parsePaging(s *brew.Search, args url.Values) error {
 	s.Limit = 100
 
+	if size := args.Get("per_page"); size != "" {
+		limit, err := strconv.Atoi(size)
+		if err != nil || limit < 1 || limit > 500 {
+			return fmt.Errorf("The per_page parameter must be between 1 and 500")
+		}
+		s.Limit = limit
+	}
+
 	pagenum := args.Get("page")
 	if pagenum == "" {
 		return nil
@@ -187,6 +343,27 @@ func parsePaging(s *brew.Search, args url.Values) error {
 	return nil
 }
 
+// Cursors can't be combined with pages and must use the same sort order as
+// the search that created them
+func parseCursor(s *brew.Search, args url.Values) error {
+	token := args.Get("cursor")
+	if token == "" {
+		return nil
+	}
+	if args.Get("page") != "" {
+		return fmt.Errorf("The cursor and page parameters can't be used together")
+	}
+	cursor, err := brew.DecodeCursor(token)
+	if err != nil {
+		return err
+	}
+	if !cursor.Matches(*s) {
+		return fmt.Errorf("The cursor doesn't match the sort order of the search")
+	}
+	s.Cursor = &cursor
+	return nil
+}
+
 func ParseSearch(u *url.URL) (brew.Search, error, []string) {
 	args := u.Query()
 	search := brew.Search{}
@@ -197,6 +374,7 @@ func ParseSearch(u *url.URL) (brew.Search, error, []string) {
 		parseTypes,
 		parseSupertypes,
 		parseColors,
+		parseIdentity,
 		parseSubtypes,
 		parseFormat,
 		parseStatus,
@@ -204,7 +382,16 @@ func ParseSearch(u *url.URL) (brew.Search, error, []string) {
 		parseSets,
 		parseName,
 		parseRules,
+		parseText,
+		parseMatches,
+		parseCMC,
+		parsePower,
+		parseToughness,
+		parseLoyalty,
+		parseQuery,
+		parseSort,
 		parsePaging,
+		parseCursor,
 	}
 
 	var err error
@@ -217,8 +404,87 @@ func ParseSearch(u *url.URL) (brew.Search, error, []string) {
 		}
 	}
 
-	// By default, include 100 cards
-	search.Limit = 100
-
 	return search, err, results
 }
+
+func parseDate(args url.Values, key string) (string, error) {
+	value := args.Get(key)
+	if value == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", fmt.Errorf("The %s date '%s' must be in YYYY-MM-DD format", key, value)
+	}
+	return value, nil
+}
+
+// ParseSetSearch reads the set filters from a URL. Types and borders match
+// exactly, blocks ignore case and from and to bound the release date.
+func ParseSetSearch(u *url.URL) (brew.SetSearch, error, []string) {
+	args := u.Query()
+	s := brew.SetSearch{
+		Types:   args["type"],
+		Borders: args["border"],
+		Blocks:  toLower(args["block"]),
+	}
+	results := []string{}
+
+	var err error
+	if s.From, err = parseDate(args, "from"); err != nil {
+		results = append(results, err.Error())
+	}
+	if s.To, err = parseDate(args, "to"); err != nil {
+		results = append(results, err.Error())
+	}
+
+	switch sort := args.Get("sort"); sort {
+	case "", "name", "released":
+		s.Sort = sort
+	default:
+		results = append(results, fmt.Sprintf("The sort '%s' is not recognized", sort))
+	}
+
+	switch order := args.Get("order"); order {
+	case "", "asc", "desc":
+		s.Order = order
+	default:
+		results = append(results, "Order should be either 'asc' or 'desc'")
+	}
+
+	if len(results) > 0 {
+		return s, fmt.Errorf("Errors while processing the search"), results
+	}
+	return s, nil, results
+}
+
+// ParsePriceHistory reads the date range and interval of a price history
+// request. Prices are grouped by day unless the interval is week.
+func ParsePriceHistory(u *url.URL) (brew.PriceHistorySearch, error, []string) {
+	args := u.Query()
+	s := brew.PriceHistorySearch{Interval: "day"}
+	results := []string{}
+
+	var err error
+	if s.From, err = parseDate(args, "from"); err != nil {
+		results = append(results, err.Error())
+	}
+	if s.To, err = parseDate(args, "to"); err != nil {
+		results = append(results, err.Error())
+	}
+	if s.From != "" && s.To != "" && s.From > s.To {
+		results = append(results, "The from date must not be after the to date")
+	}
+
+	switch interval := args.Get("interval"); interval {
+	case "":
+	case "day", "week":
+		s.Interval = interval
+	default:
+		results = append(results, fmt.Sprintf("The interval '%s' is not recognized", interval))
+	}
+
+	if len(results) > 0 {
+		return s, fmt.Errorf("Errors while processing the search"), results
+	}
+	return s, nil, results
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestParseComparisons(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?cmc=gte:2&cmc=lte:4&power=5&loyalty=gt:3")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+
+	cmc := []brew.Comparison{{Op: "gte", Value: 2}, {Op: "lte", Value: 4}}
+	if !reflect.DeepEqual(s.CMC, cmc) {
+		t.Errorf("Expected %+v not %+v", cmc, s.CMC)
+	}
+
+	power := []brew.Comparison{{Op: "eq", Value: 5}}
+	if !reflect.DeepEqual(s.Power, power) {
+		t.Errorf("Expected %+v not %+v", power, s.Power)
+	}
+
+	loyalty := []brew.Comparison{{Op: "gt", Value: 3}}
+	if !reflect.DeepEqual(s.Loyalty, loyalty) {
+		t.Errorf("Expected %+v not %+v", loyalty, s.Loyalty)
+	}
+
+	for _, bad := range []string{"cmc=foo:2", "power=*", "toughness=gte:x"} {
+		u, _ := url.Parse("/mtg/cards?" + bad)
+		if _, err, _ := ParseSearch(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
+
+func TestParseMatches(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?color=white&color=blue&color_match=subset&type_match=none&type=land")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if s.ColorMatch != brew.MatchSubset {
+		t.Errorf("Expected color_match to be subset, not %s", s.ColorMatch)
+	}
+	if s.TypeMatch != brew.MatchNone {
+		t.Errorf("Expected type_match to be none, not %s", s.TypeMatch)
+	}
+	if s.SetMatch != "" {
+		t.Errorf("Expected set_match to be empty, not %s", s.SetMatch)
+	}
+
+	u, _ = url.Parse("/mtg/cards?color_match=some")
+	if _, err, _ := ParseSearch(u); err == nil {
+		t.Errorf("Expected color_match=some to return an error")
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?identity=black&identity=green")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	identity := []string{"black", "green"}
+	if !reflect.DeepEqual(s.Identity, identity) {
+		t.Errorf("Expected %v not %v", identity, s.Identity)
+	}
+
+	u, _ = url.Parse("/mtg/cards?identity=purple")
+	if _, err, _ := ParseSearch(u); err == nil {
+		t.Errorf("Expected identity=purple to return an error")
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?sort=cmc&order=desc")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if s.Sort != "cmc" || s.Order != "desc" {
+		t.Errorf("Expected to sort by cmc desc, not %s %s", s.Sort, s.Order)
+	}
+
+	for _, bad := range []string{"sort=color", "order=up"} {
+		u, _ := url.Parse("/mtg/cards?" + bad)
+		if _, err, _ := ParseSearch(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
+
+func TestParsePaging(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?page=2&per_page=50")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if s.Limit != 50 || s.Offset != 100 {
+		t.Errorf("Expected limit 50 and offset 100, not %d and %d", s.Limit, s.Offset)
+	}
+
+	u, _ = url.Parse("/mtg/cards")
+	s, _, _ = ParseSearch(u)
+	if s.Limit != 100 {
+		t.Errorf("Expected a default limit of 100, not %d", s.Limit)
+	}
+
+	for _, bad := range []string{"per_page=0", "per_page=501", "per_page=all"} {
+		u, _ := url.Parse("/mtg/cards?" + bad)
+		if _, err, _ := ParseSearch(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	value := "3"
+	cursor := brew.Cursor{Sort: "cmc", Order: "desc", Value: &value, Id: "time-vault"}
+
+	u, _ := url.Parse("/mtg/cards?sort=cmc&order=desc&cursor=" + cursor.Encode())
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if s.Cursor == nil || !reflect.DeepEqual(*s.Cursor, cursor) {
+		t.Errorf("Expected cursor %+v not %+v", cursor, s.Cursor)
+	}
+
+	for _, bad := range []string{
+		"cursor=garbage",
+		"sort=name&cursor=" + cursor.Encode(),
+		"sort=cmc&order=desc&page=2&cursor=" + cursor.Encode(),
+	} {
+		u, _ := url.Parse("/mtg/cards?" + bad)
+		if _, err, _ := ParseSearch(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
+
+func TestParseText(t *testing.T) {
+	u, _ := url.Parse("/mtg/cards?text=draws+a+card&sort=relevance")
+	s, err, errors := ParseSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	if !reflect.DeepEqual(s.Text, []string{"draws a card"}) {
+		t.Errorf("Expected text to be 'draws a card', not %v", s.Text)
+	}
+	if s.Sort != "relevance" {
+		t.Errorf("Expected to sort by relevance, not %s", s.Sort)
+	}
+
+	u, _ = url.Parse("/mtg/cards?sort=relevance")
+	if _, err, _ := ParseSearch(u); err == nil {
+		t.Errorf("Expected sorting by relevance without text to return an error")
+	}
+}
+
+func TestParseSetSearch(t *testing.T) {
+	u, _ := url.Parse("/mtg/sets?type=expansion&border=black&block=Alara&from=2008-01-01&to=2009-12-31&sort=released&order=desc")
+	s, err, errors := ParseSetSearch(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	expected := brew.SetSearch{
+		Types:   []string{"expansion"},
+		Borders: []string{"black"},
+		Blocks:  []string{"alara"},
+		From:    "2008-01-01",
+		To:      "2009-12-31",
+		Sort:    "released",
+		Order:   "desc",
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected %+v not %+v", expected, s)
+	}
+
+	for _, bad := range []string{
+		"from=2008",
+		"to=yesterday",
+		"sort=size",
+		"order=up",
+	} {
+		u, _ := url.Parse("/mtg/sets?" + bad)
+		if _, err, _ := ParseSetSearch(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
+
+func TestParsePriceHistory(t *testing.T) {
+	u, _ := url.Parse("/mtg/editions/12414/prices?from=2015-01-01&to=2015-03-31&interval=week")
+	s, err, errors := ParsePriceHistory(u)
+	if err != nil {
+		t.Fatal(errors)
+	}
+	expected := brew.PriceHistorySearch{From: "2015-01-01", To: "2015-03-31", Interval: "week"}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected %+v not %+v", expected, s)
+	}
+
+	u, _ = url.Parse("/mtg/editions/12414/prices")
+	if s, _, _ := ParsePriceHistory(u); s.Interval != "day" {
+		t.Errorf("Expected the interval to default to day, not %s", s.Interval)
+	}
+
+	for _, bad := range []string{
+		"interval=month",
+		"from=01/01/2015",
+		"from=2015-03-01&to=2015-01-01",
+	} {
+		u, _ := url.Parse("/mtg/editions/12414/prices?" + bad)
+		if _, err, _ := ParsePriceHistory(u); err == nil {
+			t.Errorf("Expected %s to return an error", bad)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+// DeckStats describes the main deck and commander. Sideboards aren't
+// counted. Prices are in cents and only include cards with a known price.
+type DeckStats struct {
+	Cards      int            `json:"cards"`
+	Lands      int            `json:"lands"`
+	Nonlands   int            `json:"nonlands"`
+	AverageCMC float64        `json:"average_cmc"`
+	Curve      map[string]int `json:"curve"`
+	Colors     map[string]int `json:"colors"`
+	Types      map[string]int `json:"types"`
+	Price      brew.Price     `json:"price"`
+	Unpriced   int            `json:"unpriced"`
+}
+
+type StatsResult struct {
+	Stats  DeckStats   `json:"stats"`
+	Errors []DeckError `json:"errors"`
+}
+
+// manaPips counts the colored mana symbols in a mana cost. Hybrid symbols
+// count towards each of their colors.
+func manaPips(cost string) map[string]int {
+	pips := map[string]int{}
+	for _, color := range symbolPips(cost) {
+		pips[color] += 1
+	}
+	return pips
+}
+
+// DeckStatistics returns the mana curve, color and type breakdown and total
+// price of a deck. Cards without a chosen printing are priced at their latest
+// printing.
+func DeckStatistics(deck []brew.DeckCard) DeckStats {
+	stats := DeckStats{
+		Curve:  map[string]int{},
+		Colors: map[string]int{},
+		Types:  map[string]int{},
+	}
+	cmc := 0
+	updated := ""
+
+	for i := range deck {
+		d := &deck[i]
+		if d.Board == brew.BoardSide {
+			continue
+		}
+		c := &d.Card
+		stats.Cards += d.Quantity
+
+		for _, t := range c.Types {
+			stats.Types[t] += d.Quantity
+		}
+		for color, n := range manaPips(c.ManaCost) {
+			stats.Colors[color] += n * d.Quantity
+		}
+
+		if hasType(c.Types, "land") {
+			stats.Lands += d.Quantity
+		} else {
+			stats.Nonlands += d.Quantity
+			stats.Curve[strconv.Itoa(c.ConvertedCost)] += d.Quantity
+			cmc += c.ConvertedCost * d.Quantity
+		}
+
+		e := d.Edition()
+		if e == nil || e.Price == nil {
+			stats.Unpriced += d.Quantity
+			continue
+		}
+		stats.Price.Low += e.Price.Low * d.Quantity
+		stats.Price.Average += e.Price.Average * d.Quantity
+		stats.Price.High += e.Price.High * d.Quantity
+		if e.Price.UpdatedAt > updated {
+			updated = e.Price.UpdatedAt
+		}
+	}
+
+	stats.Price.UpdatedAt = updated
+	if stats.Nonlands > 0 {
+		average := float64(cmc) / float64(stats.Nonlands)
+		stats.AverageCMC = math.Floor(average*100+0.5) / 100
+	}
+	return stats
+}
+
+func (a *API) HandleDeckStats(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req DeckRequest
+	if err := readDeckRequest(w, r, &req); err != nil || strings.TrimSpace(req.Decklist) == "" {
+		JSON(w, http.StatusBadRequest, Errors("The request body must be a JSON object with a decklist"))
+		return
+	}
+	deck, errors, err := a.resolveDecklist(ctx, req.Decklist)
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, Errors("Error fetching cards"))
+		return
+	}
+	JSON(w, http.StatusOK, StatsResult{Stats: DeckStatistics(deck), Errors: errors})
+}
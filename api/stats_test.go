@@ -0,0 +1,48 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/brew"
+)
+
+func TestManaPips(t *testing.T) {
+	pips := manaPips("{2}{W}{W}{W/U}{G/P}{HR}{C}")
+	expected := map[string]int{"white": 3, "blue": 1, "green": 1, "red": 1}
+	if !reflect.DeepEqual(pips, expected) {
+		t.Errorf("Expected %v not %v", expected, pips)
+	}
+}
+
+func TestDeckStatistics(t *testing.T) {
+	price := &brew.Price{Low: 10, Average: 50, High: 100, UpdatedAt: "2015-01-20T08:00:00"}
+	bolt := brew.Card{Id: "bolt", Types: []string{"instant"}, ConvertedCost: 1, ManaCost: "{R}",
+		Editions: []brew.Edition{{MultiverseId: 1, Price: price}}}
+	helix := brew.Card{Id: "helix", Types: []string{"instant"}, ConvertedCost: 2, ManaCost: "{R}{W}",
+		Editions: []brew.Edition{{MultiverseId: 2}}}
+	dryad := brew.Card{Id: "dryad-arbor", Types: []string{"land", "creature"},
+		Editions: []brew.Edition{{MultiverseId: 3, Price: price}}}
+
+	stats := DeckStatistics([]brew.DeckCard{
+		{Quantity: 4, Board: brew.BoardMain, Card: bolt},
+		{Quantity: 2, Board: brew.BoardMain, Card: helix},
+		{Quantity: 1, Board: brew.BoardMain, Card: dryad},
+		{Quantity: 3, Board: brew.BoardSide, Card: helix},
+	})
+
+	expected := DeckStats{
+		Cards:      7,
+		Lands:      1,
+		Nonlands:   6,
+		AverageCMC: 1.33,
+		Curve:      map[string]int{"1": 4, "2": 2},
+		Colors:     map[string]int{"red": 6, "white": 2},
+		Types:      map[string]int{"instant": 6, "land": 1, "creature": 1},
+		Price:      brew.Price{Low: 50, Average: 250, High: 500, UpdatedAt: "2015-01-20T08:00:00"},
+		Unpriced:   2,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v not %+v", expected, stats)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/kyleconroy/deckbrew/brew"
+	"github.com/kyleconroy/deckbrew/config"
+)
+
+const queryUpsertTCGName = `
+INSERT INTO tcg_names (multiverse_id, name) VALUES ($1, $2)
+ON CONFLICT (multiverse_id) DO UPDATE SET name = EXCLUDED.name
+`
+
+const queryUpsertTCGSet = `
+INSERT INTO tcg_sets (set_id, name) VALUES ($1, $2)
+ON CONFLICT (set_id) DO UPDATE SET name = EXCLUDED.name
+`
+
+const queryDeleteTCGName = `
+DELETE FROM tcg_names WHERE multiverse_id = $1
+`
+
+const queryDeleteTCGSet = `
+DELETE FROM tcg_sets WHERE set_id = $1
+`
+
+// tcgKey validates the kind of a mapping and its key, which is a multiverse
+// id for names and a set id for sets. Surrounding whitespace is ignored.
+func tcgKey(kind, key string) (interface{}, error) {
+	key = strings.TrimSpace(key)
+	switch kind {
+	case "name":
+		id, err := strconv.Atoi(key)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("name mappings are keyed by multiverse id, not '%s'", key)
+		}
+		return id, nil
+	case "set":
+		if key == "" {
+			return nil, fmt.Errorf("set mappings are keyed by set id, which can't be empty")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("mappings are either 'name' or 'set', not '%s'", kind)
+}
+
+// ListTCGMappings prints every TCGplayer name and set override
+func ListTCGMappings() error {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return err
+	}
+	m, err := brew.LoadTCGMap(context.TODO(), cfg.DB)
+	if err != nil {
+		return err
+	}
+
+	ids := []int{}
+	for id := range m.Names {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		fmt.Printf("name\t%d\t%s\n", id, m.Names[id])
+	}
+
+	sets := []string{}
+	for id := range m.Sets {
+		sets = append(sets, id)
+	}
+	sort.Strings(sets)
+	for _, id := range sets {
+		fmt.Printf("set\t%s\t%s\n", id, m.Sets[id])
+	}
+	return nil
+}
+
+// AddTCGMapping adds or replaces a TCGplayer name or set override
+func AddTCGMapping(kind, key, name string) error {
+	id, err := tcgKey(kind, key)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return err
+	}
+	query := queryUpsertTCGName
+	if kind == "set" {
+		query = queryUpsertTCGSet
+	}
+	_, err = cfg.DB.Exec(query, id, name)
+	return err
+}
+
+// RemoveTCGMapping deletes a TCGplayer name or set override
+func RemoveTCGMapping(kind, key string) error {
+	id, err := tcgKey(kind, key)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return err
+	}
+	query := queryDeleteTCGName
+	if kind == "set" {
+		query = queryDeleteTCGSet
+	}
+	res, err := cfg.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no %s mapping for %s", kind, key)
+	}
+	return nil
+}
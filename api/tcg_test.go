@@ -0,0 +1,36 @@
+package api
+
+import "testing"
+
+func TestTCGKey(t *testing.T) {
+	for _, test := range []struct {
+		kind, key string
+		expected  interface{}
+	}{
+		{"name", "370603", 370603},
+		{"name", " 370603 ", 370603},
+		{"set", "MMA", "MMA"},
+		{"set", " DD3_GVL\n", "DD3_GVL"},
+	} {
+		key, err := tcgKey(test.kind, test.key)
+		if err != nil {
+			t.Errorf("%s %q: %s", test.kind, test.key, err)
+			continue
+		}
+		if key != test.expected {
+			t.Errorf("%s %q: expected %#v not %#v", test.kind, test.key, test.expected, key)
+		}
+	}
+
+	for _, test := range []struct{ kind, key string }{
+		{"name", "lightning-bolt"},
+		{"name", "-1"},
+		{"name", ""},
+		{"set", "  "},
+		{"card", "MMA"},
+	} {
+		if _, err := tcgKey(test.kind, test.key); err == nil {
+			t.Errorf("Expected %s %q to return an error", test.kind, test.key)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/kyleconroy/deckbrew/config"
@@ -13,22 +14,77 @@ import (
 	"stackmachine.com/cql"
 )
 
+const setColumns = `
+id, name, border, type, coalesce(to_char(released, 'YYYY-MM-DD'), ''), block, size, price_guide, priced
+`
+
 const querySet = `
-SELECT id, name, border, type FROM sets WHERE id = $1
+SELECT ` + setColumns + ` FROM sets WHERE id = $1
 `
 
 const querySets = `
-SELECT id, name, border, type, price_guide, priced FROM sets ORDER BY name
+SELECT ` + setColumns + ` FROM sets
+WHERE %s
+ORDER BY %s
 `
 
 const queryTypeahead = `
 SELECT record FROM cards WHERE name ILIKE $1 ORDER BY name LIMIT 10
 `
 
+const queryResolve = `
+SELECT record, similarity(immutable_unaccent(lower(name)), immutable_unaccent(lower($1))) AS score
+FROM cards
+WHERE immutable_unaccent(lower(name)) % immutable_unaccent(lower($1))
+ORDER BY score DESC, name ASC
+LIMIT 6
+`
+
+const queryCollection = `
+SELECT record FROM cards
+WHERE id = ANY($1)
+   OR id IN (SELECT card_id FROM card_redirects WHERE id = ANY($6))
+   OR mids && $2
+   OR lower(name) = ANY($3)
+   OR (sets && $4 AND EXISTS (
+     SELECT 1 FROM json_array_elements(record::json->'editions') AS e
+     WHERE lower(e->>'set_id') || '/' || (e->>'number') = ANY($5)))
+`
+
+const queryCardRedirect = `
+SELECT card_id FROM card_redirects WHERE id = $1
+`
+
 const queryCard = `
 SELECT record FROM cards WHERE id = $1
 `
 
+const queryRulings = `
+SELECT card_id, coalesce(to_char(date, 'YYYY-MM-DD'), ''), text FROM rulings
+WHERE card_id = ANY($1)
+ORDER BY card_id, date ASC
+`
+
+const queryPrices = `
+SELECT DISTINCT ON (multiverse_id, foil)
+  multiverse_id, foil, low, median, high,
+  to_char(created, 'YYYY-MM-DD"T"HH24:MI:SS')
+FROM prices
+WHERE multiverse_id = ANY($1)
+ORDER BY multiverse_id, foil, created DESC
+`
+
+const queryPriceHistory = `
+SELECT to_char(date_trunc($2, created), 'YYYY-MM-DD') AS period, foil,
+  min(low), round(percentile_cont(0.5) WITHIN GROUP (ORDER BY median))::integer, max(high)
+FROM prices
+WHERE multiverse_id = ANY($1)
+  AND created >= coalesce(nullif($3, '')::date, '-infinity')
+  AND created < coalesce(nullif($4, '')::date + 1, 'infinity')
+GROUP BY period, foil
+ORDER BY period ASC
+`
+
 const queryColors = `
 SELECT DISTINCT unnest(colors) as t from cards WHERE NOT sets && '{unh,ugl}' ORDER BY t ASC
 `
@@ -50,23 +106,15 @@ SELECT id FROM cards ORDER BY RANDOM() LIMIT 1
 `
 
 const queryCards = `
-SELECT record FROM cards
-WHERE
-  ($1 OR multicolor = $2) AND
-  ($3 OR rarities && $4) AND
-  ($5 OR types && $6) AND 
-  ($7 OR supertypes && $8) AND 
-  ($9 OR colors && $10) AND 
-  ($11 OR subtypes && $12) AND 
-  ($13 OR formats && $14) AND 
-  ($15 OR status && $16) AND 
-  ($17 OR mids && $18) AND 
-  ($19 OR sets && $20) AND 
-  ($21 OR name ILIKE ANY ($22)) AND
-  ($23 OR rules ILIKE ANY ($24))
-ORDER BY name ASC
-LIMIT $25
-OFFSET $26
+SELECT record, (%s)::text, id FROM cards
+WHERE (%s) AND %s
+ORDER BY %s
+LIMIT %s
+OFFSET %s
+`
+
+const queryCountCards = `
+SELECT count(*) FROM cards WHERE %s
 `
 
 type client struct {
@@ -75,10 +123,14 @@ type client struct {
 
 	// Prepared statements
 	stmtGetSet        *cql.Stmt
-	stmtGetSets       *cql.Stmt
 	stmtTypeahead     *cql.Stmt
+	stmtResolve       *cql.Stmt
 	stmtGetCard       *cql.Stmt
-	stmtGetCards      *cql.Stmt
+	stmtRedirect      *cql.Stmt
+	stmtCollection    *cql.Stmt
+	stmtRulings       *cql.Stmt
+	stmtPrices        *cql.Stmt
+	stmtPriceHistory  *cql.Stmt
 	stmtGetTypes      *cql.Stmt
 	stmtGetSubtypes   *cql.Stmt
 	stmtGetSupertypes *cql.Stmt
@@ -87,18 +139,29 @@ type client struct {
 }
 
 func NewReader(cfg *config.Config) (Reader, error) {
-	c := &client{db: cfg.DB, router: router{cfg}}
-	var err error
+	tcg, err := LoadTCGMap(context.TODO(), cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := storeLinkers(cfg, tcg)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{db: cfg.DB, router: router{cfg: cfg, stores: stores}}
 
 	for _, pair := range []struct {
 		stmt  **cql.Stmt
 		query string
 	}{
 		{&c.stmtGetSet, querySet},
-		{&c.stmtGetSets, querySets},
 		{&c.stmtGetCard, queryCard},
-		{&c.stmtGetCards, queryCards},
+		{&c.stmtRedirect, queryCardRedirect},
+		{&c.stmtCollection, queryCollection},
+		{&c.stmtRulings, queryRulings},
+		{&c.stmtPrices, queryPrices},
+		{&c.stmtPriceHistory, queryPriceHistory},
 		{&c.stmtTypeahead, queryTypeahead},
+		{&c.stmtResolve, queryResolve},
 		{&c.stmtGetColors, queryColors},
 		{&c.stmtGetTypes, queryTypes},
 		{&c.stmtGetSupertypes, querySupertypes},
@@ -113,29 +176,37 @@ func NewReader(cfg *config.Config) (Reader, error) {
 	return c, nil
 }
 
+func scanSet(row interface {
+	Scan(...interface{}) error
+}, set *Set) error {
+	return row.Scan(&set.Id, &set.Name, &set.Border, &set.Type, &set.Released,
+		&set.Block, &set.Size, &set.PriceGuide, &set.Priced)
+}
+
 func (c *client) GetSet(ctx context.Context, id string) (Set, error) {
 	var set Set
-	row := c.stmtGetSet.QueryRowC(ctx, id)
-	err := row.Scan(&set.Id, &set.Name, &set.Border, &set.Type)
+	err := scanSet(c.stmtGetSet.QueryRowC(ctx, id), &set)
+	set.Fill(c.router)
 	return set, err
 }
 
-func (c *client) GetSets(ctx context.Context) ([]Set, error) {
+func (c *client) GetSets(ctx context.Context, s SetSearch) ([]Set, error) {
 	sets := []Set{}
-	rows, err := c.stmtGetSets.QueryC(ctx)
+	q := query{}
+	rows, err := c.db.QueryC(ctx, fmt.Sprintf(querySets, q.setWhere(s), setOrder(s)), q.args...)
 	if err != nil {
 		return sets, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var set Set
-		if err := rows.Scan(&set.Id, &set.Name, &set.Border, &set.Type, &set.PriceGuide, &set.Priced); err != nil {
+		if err := scanSet(rows, &set); err != nil {
 			return sets, err
 		}
 		set.Fill(c.router)
 		sets = append(sets, set)
 	}
-	return sets, nil
+	return sets, rows.Err()
 }
 
 func scanCards(rows *sql.Rows, r router) ([]Card, error) {
@@ -181,39 +252,138 @@ func (c *client) GetCardsByName(ctx context.Context, search string) ([]Card, err
 		return []Card{}, err
 	}
 
-	return scanCards(rows, c.router)
+	cards, err := scanCards(rows, c.router)
+	if err != nil {
+		return cards, err
+	}
+	return cards, c.fillPrices(ctx, cards)
+}
+
+func (c *client) ResolveCardName(ctx context.Context, name string) (Resolution, error) {
+	var resolution Resolution
+
+	rows, err := c.stmtResolve.QueryC(ctx, name)
+	if err != nil {
+		return resolution, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blob []byte
+		var card Card
+		var score float64
+
+		if err := rows.Scan(&blob, &score); err != nil {
+			return resolution, err
+		}
+		if err := json.Unmarshal(blob, &card); err != nil {
+			return resolution, err
+		}
+		card.Fill(c.router)
+
+		if resolution.Card.Id == "" {
+			resolution.Card = card
+			resolution.Score = score
+			resolution.Alternatives = []CardMatch{}
+			continue
+		}
+		resolution.Alternatives = append(resolution.Alternatives, CardMatch{
+			Name:  card.Name,
+			Id:    card.Id,
+			Href:  card.Href,
+			Score: score,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return resolution, err
+	}
+	if resolution.Card.Id == "" {
+		return resolution, fmt.Errorf("No card matches the name %s", name)
+	}
+	return resolution, c.fillPrices(ctx, []Card{resolution.Card})
 }
 
 func sarray(values []string) string {
 	return "{" + strings.Join(values, ",") + "}"
 }
 
-func (c *client) GetCards(ctx context.Context, s Search) ([]Card, error) {
-	rows, err := c.stmtGetCards.QueryC(ctx,
-		// Multicolor, ignored by default
-		!s.IncludeMulticolor, s.Multicolor,
-		len(s.Rarities) == 0, sarray(s.Rarities),
-		len(s.Types) == 0, sarray(s.Types),
-		len(s.Supertypes) == 0, sarray(s.Supertypes),
-		len(s.Colors) == 0, sarray(s.Colors),
-		len(s.Subtypes) == 0, sarray(s.Subtypes),
-		len(s.Formats) == 0, sarray(s.Formats),
-		len(s.Status) == 0, sarray(s.Status),
-		len(s.MultiverseIDs) == 0, sarray(s.MultiverseIDs),
-		len(s.Sets) == 0, sarray(s.Sets),
-		len(s.Names) == 0, sarray(s.Names),
-		len(s.Rules) == 0, sarray(s.Rules),
-		s.Limit, s.Offset,
-	)
-	if err == sql.ErrNoRows {
-		return []Card{}, nil
+// quotedArray is like sarray, but safe for values containing commas, quotes
+// or spaces, such as card names
+func quotedArray(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		v = strings.Replace(v, `\`, `\\`, -1)
+		v = strings.Replace(v, `"`, `\"`, -1)
+		quoted = append(quoted, `"`+v+`"`)
 	}
+	return sarray(quoted)
+}
+
+func (c *client) GetCards(ctx context.Context, s Search) ([]Card, error) {
+	cards, _, err := c.GetCardPage(ctx, s)
+	return cards, err
+}
+
+// GetCardPage returns a page of cards and a cursor pointing at the last card,
+// or nil if there are no more cards after this page.
+func (c *client) GetCardPage(ctx context.Context, s Search) ([]Card, *Cursor, error) {
+	sort, order := sortKey(s)
+
+	// Fetch an extra card to find out if there is another page
+	q := query{}
+	column, _ := q.sortColumn(s)
+	statement := fmt.Sprintf(queryCards, column.expr, q.where(s), q.after(s),
+		q.order(s), q.param(s.Limit+1), q.param(s.Offset))
+
+	rows, err := c.db.QueryC(ctx, statement, q.args...)
 	if err != nil {
 		log.Println(err)
-		return []Card{}, err
+		return []Card{}, nil, err
 	}
+	defer rows.Close()
 
-	return scanCards(rows, c.router)
+	cards := []Card{}
+	cursors := []Cursor{}
+	for rows.Next() {
+		var blob []byte
+		var card Card
+		var key sql.NullString
+		var id string
+
+		if err := rows.Scan(&blob, &key, &id); err != nil {
+			return []Card{}, nil, err
+		}
+		if err := json.Unmarshal(blob, &card); err != nil {
+			return []Card{}, nil, err
+		}
+
+		cursor := Cursor{Sort: sort, Order: order, Id: id}
+		if key.Valid {
+			cursor.Value = &key.String
+		}
+		cards = append(cards, card)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return []Card{}, nil, err
+	}
+
+	var next *Cursor
+	if s.Limit > 0 && len(cards) > s.Limit {
+		cards = cards[:s.Limit]
+		next = &cursors[s.Limit-1]
+	}
+	for i, _ := range cards {
+		cards[i].Fill(c.router)
+	}
+	return cards, next, c.fillPrices(ctx, cards)
+}
+
+func (c *client) CountCards(ctx context.Context, s Search) (int, error) {
+	var count int
+	q := query{}
+	err := c.db.QueryRowC(ctx, fmt.Sprintf(queryCountCards, q.where(s)), q.args...).Scan(&count)
+	return count, err
 }
 
 func (c *client) GetRandomCardID(ctx context.Context) (string, error) {
@@ -242,7 +412,166 @@ func (c *client) GetCard(ctx context.Context, id string) (Card, error) {
 		return card, err
 	}
 	card.Fill(c.router)
-	return card, nil
+	return card, c.fillPrices(ctx, []Card{card})
+}
+
+// GetCardCollection fetches the cards for a list of identifiers in a single
+// query. Cards are returned in the same order as the identifiers, followed by
+// the identifiers that didn't match a card.
+func (c *client) GetCardCollection(ctx context.Context, ids []CardIdentifier) ([]Card, []CardIdentifier, error) {
+	// Old face ids and face names are found using redirects
+	var slugs, faces, mids, names, sets, numbers []string
+	for _, i := range ids {
+		switch {
+		case i.Id != "":
+			slugs = append(slugs, i.Id)
+			faces = append(faces, i.Id)
+		case i.Name != "":
+			names = append(names, strings.ToLower(i.Name))
+			faces = append(faces, Slug(i.Name))
+		case i.MultiverseId != 0:
+			mids = append(mids, strconv.Itoa(i.MultiverseId))
+		case i.Set != "":
+			sets = append(sets, strings.ToLower(i.Set))
+			numbers = append(numbers, strings.ToLower(i.Set)+"/"+i.Number)
+		}
+	}
+
+	rows, err := c.stmtCollection.QueryC(ctx, quotedArray(slugs), quotedArray(mids),
+		quotedArray(names), quotedArray(sets), quotedArray(numbers), quotedArray(faces))
+	if err != nil {
+		return []Card{}, ids, err
+	}
+	found, err := scanCards(rows, c.router)
+	if err != nil {
+		return []Card{}, ids, err
+	}
+	if err := c.fillPrices(ctx, found); err != nil {
+		return []Card{}, ids, err
+	}
+
+	cards := []Card{}
+	missing := []CardIdentifier{}
+	for _, i := range ids {
+		matched := false
+		for j := range found {
+			if i.Matches(&found[j]) {
+				cards = append(cards, found[j])
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, i)
+		}
+	}
+	return cards, missing, nil
+}
+
+// fillPrices sets the latest regular and foil price on every edition of the
+// given cards. Editions without a known price are left without one.
+func (c *client) fillPrices(ctx context.Context, cards []Card) error {
+	mids := []string{}
+	for _, card := range cards {
+		for _, e := range card.Editions {
+			mids = append(mids, strconv.Itoa(e.MultiverseId))
+		}
+	}
+	if len(mids) == 0 {
+		return nil
+	}
+
+	rows, err := c.stmtPrices.QueryC(ctx, sarray(mids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	regular := map[string]*Price{}
+	foil := map[string]*Price{}
+	for rows.Next() {
+		var id string
+		var isFoil bool
+		var p Price
+		if err := rows.Scan(&id, &isFoil, &p.Low, &p.Average, &p.High, &p.UpdatedAt); err != nil {
+			return err
+		}
+		if isFoil {
+			foil[id] = &p
+		} else {
+			regular[id] = &p
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range cards {
+		for j := range cards[i].Editions {
+			e := &cards[i].Editions[j]
+			id := strconv.Itoa(e.MultiverseId)
+			e.Price = regular[id]
+			e.FoilPrice = foil[id]
+		}
+	}
+	return nil
+}
+
+// GetPriceHistory rolls up the price snapshots of every given edition
+func (c *client) GetPriceHistory(ctx context.Context, s PriceHistorySearch) (PriceHistory, error) {
+	history := PriceHistory{Interval: s.Interval, Prices: []PricePoint{}, FoilPrices: []PricePoint{}}
+	if history.Interval == "" {
+		history.Interval = "day"
+	}
+
+	rows, err := c.stmtPriceHistory.QueryC(ctx, sarray(s.MultiverseIDs), history.Interval, s.From, s.To)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p PricePoint
+		var foil bool
+		if err := rows.Scan(&p.Date, &foil, &p.Low, &p.Average, &p.High); err != nil {
+			return history, err
+		}
+		if foil {
+			history.FoilPrices = append(history.FoilPrices, p)
+		} else {
+			history.Prices = append(history.Prices, p)
+		}
+	}
+	return history, rows.Err()
+}
+
+// GetRulings returns the rulings for each of the given card ids, oldest
+// first. Cards without rulings are left out of the result.
+func (c *client) GetRulings(ctx context.Context, ids []string) (map[string][]Ruling, error) {
+	rulings := map[string][]Ruling{}
+
+	rows, err := c.stmtRulings.QueryC(ctx, quotedArray(ids))
+	if err != nil {
+		return rulings, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var r Ruling
+		if err := rows.Scan(&id, &r.Date, &r.Text); err != nil {
+			return rulings, err
+		}
+		rulings[id] = append(rulings[id], r)
+	}
+	return rulings, rows.Err()
+}
+
+func (c *client) GetCardRedirect(ctx context.Context, id string) (string, error) {
+	var to string
+	err := c.stmtRedirect.QueryRowC(ctx, id).Scan(&to)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("No redirect for card ID %s", id)
+	}
+	return to, err
 }
 
 func (c *client) fetchTerms(ctx context.Context, stmt *cql.Stmt) ([]string, error) {
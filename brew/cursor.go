@@ -0,0 +1,41 @@
+package brew
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// A Cursor points at the last card of a page of search results. Value holds
+// the card's sort column as text and is nil when the card has no value.
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Order string  `json:"o"`
+	Value *string `json:"v"`
+	Id    string  `json:"i"`
+}
+
+// Encode returns an opaque, URL safe representation of the cursor
+func (c Cursor) Encode() string {
+	blob, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(blob)
+}
+
+// Matches reports whether the cursor was created for a search with the same
+// sort order
+func (c Cursor) Matches(s Search) bool {
+	sort, order := sortKey(s)
+	return c.Sort == sort && c.Order == order
+}
+
+func DecodeCursor(token string) (Cursor, error) {
+	var c Cursor
+	blob, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, fmt.Errorf("The cursor '%s' is not valid", token)
+	}
+	if err := json.Unmarshal(blob, &c); err != nil || c.Id == "" {
+		return c, fmt.Errorf("The cursor '%s' is not valid", token)
+	}
+	return c, nil
+}
@@ -0,0 +1,282 @@
+package brew
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kyleconroy/deckbrew/config"
+
+	"golang.org/x/net/context"
+	"stackmachine.com/cql"
+)
+
+// The zones of a deck
+const (
+	BoardMain      = "mainboard"
+	BoardSide      = "sideboard"
+	BoardCommander = "commander"
+)
+
+// A DeckCard is a number of copies of a card in one zone of a deck. If a
+// specific printing was asked for, MultiverseId is the id of that edition.
+type DeckCard struct {
+	Quantity     int    `json:"quantity"`
+	Board        string `json:"board"`
+	MultiverseId int    `json:"multiverse_id,omitempty"`
+	Card         Card   `json:"card"`
+}
+
+// Edition returns the chosen printing of the card. If none was chosen it
+// returns the first edition, which is the card's latest printing.
+func (d *DeckCard) Edition() *Edition {
+	for i := range d.Card.Editions {
+		if d.MultiverseId != 0 && d.Card.Editions[i].HasMultiverseId(d.MultiverseId) {
+			return &d.Card.Editions[i]
+		}
+	}
+	if len(d.Card.Editions) == 0 {
+		return nil
+	}
+	return &d.Card.Editions[0]
+}
+
+// A DeckCardRef refers to a card in a saved deck by id or multiverse id. A
+// multiverse id also picks the printing.
+type DeckCardRef struct {
+	Quantity     int    `json:"quantity"`
+	Board        string `json:"board"`
+	Id           string `json:"id,omitempty"`
+	MultiverseId int    `json:"multiverse_id,omitempty"`
+}
+
+// A Deck is a saved decklist. Cards are stored as references and filled in
+// with the current card data when the deck is read.
+type Deck struct {
+	Id      string     `json:"id"`
+	Name    string     `json:"name"`
+	Format  string     `json:"format,omitempty"`
+	Href    string     `json:"url"`
+	Created string     `json:"created"`
+	Updated string     `json:"updated"`
+	Cards   []DeckCard `json:"cards"`
+
+	// Saved cards that no longer exist, such as renamed cards
+	Missing []DeckCardRef `json:"missing_cards,omitempty"`
+}
+
+type DeckStore interface {
+	GetDeck(context.Context, string) (Deck, error)
+	CreateDeck(context.Context, Deck) (Deck, error)
+	UpdateDeck(context.Context, Deck) (Deck, error)
+	DeleteDeck(context.Context, string) error
+}
+
+// ErrDeckNotFound is returned when a deck doesn't exist
+var ErrDeckNotFound = errors.New("deck not found")
+
+const queryDeck = `
+SELECT id, name, format,
+  to_char(created, 'YYYY-MM-DD"T"HH24:MI:SS'), to_char(updated, 'YYYY-MM-DD"T"HH24:MI:SS')
+FROM decks WHERE id = $1
+`
+
+const queryDeckCards = `
+SELECT board, card_id, multiverse_id, quantity FROM deck_cards
+WHERE deck_id = $1
+ORDER BY position ASC
+`
+
+const queryInsertDeck = `
+INSERT INTO decks (id, name, format) VALUES ($1, $2, $3)
+`
+
+const queryUpdateDeck = `
+UPDATE decks SET (name, format, updated) = ($1, $2, now()) WHERE id = $3
+`
+
+const queryDeleteDeck = `
+DELETE FROM decks WHERE id = $1
+`
+
+const queryDeleteDeckCards = `
+DELETE FROM deck_cards WHERE deck_id = $1
+`
+
+const queryInsertDeckCard = `
+INSERT INTO deck_cards (deck_id, position, board, card_id, multiverse_id, quantity)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type deckStore struct {
+	db     *cql.DB
+	r      Reader
+	router router
+}
+
+// NewDeckStore saves decks in the database. Cards are read back using the
+// given reader.
+func NewDeckStore(cfg *config.Config, r Reader) DeckStore {
+	return &deckStore{db: cfg.DB, r: r, router: router{cfg: cfg}}
+}
+
+func newDeckID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *deckStore) GetDeck(ctx context.Context, id string) (Deck, error) {
+	var d Deck
+	err := s.db.QueryRowC(ctx, queryDeck, id).Scan(&d.Id, &d.Name, &d.Format, &d.Created, &d.Updated)
+	if err == sql.ErrNoRows {
+		return d, ErrDeckNotFound
+	}
+	if err != nil {
+		return d, err
+	}
+	d.Href = s.router.DeckURL(d.Id)
+	d.Cards = []DeckCard{}
+
+	rows, err := s.db.QueryC(ctx, queryDeckCards, id)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	refs := []DeckCard{}
+	ids := []CardIdentifier{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var ref DeckCard
+		if err := rows.Scan(&ref.Board, &ref.Card.Id, &ref.MultiverseId, &ref.Quantity); err != nil {
+			return d, err
+		}
+		refs = append(refs, ref)
+		if !seen[ref.Card.Id] {
+			seen[ref.Card.Id] = true
+			ids = append(ids, CardIdentifier{Id: ref.Card.Id})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return d, err
+	}
+	if len(ids) == 0 {
+		return d, nil
+	}
+
+	cards, _, err := s.r.GetCardCollection(ctx, ids)
+	if err != nil {
+		return d, err
+	}
+	d.Cards, d.Missing = fillDeckCards(refs, cards)
+	return d, nil
+}
+
+// fillDeckCards replaces each saved reference with its card. References to
+// cards that can't be found are returned separately instead of being dropped.
+func fillDeckCards(refs []DeckCard, cards []Card) ([]DeckCard, []DeckCardRef) {
+	found := []DeckCard{}
+	missing := []DeckCardRef{}
+	for _, ref := range refs {
+		id := CardIdentifier{Id: ref.Card.Id}
+		matched := false
+		for i := range cards {
+			if id.Matches(&cards[i]) {
+				ref.Card = cards[i]
+				found = append(found, ref)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, DeckCardRef{
+				Quantity:     ref.Quantity,
+				Board:        ref.Board,
+				Id:           ref.Card.Id,
+				MultiverseId: ref.MultiverseId,
+			})
+		}
+	}
+	return found, missing
+}
+
+func (s *deckStore) insertCards(tx *sql.Tx, d Deck) error {
+	for i, c := range d.Cards {
+		_, err := tx.Exec(queryInsertDeckCard, d.Id, i, c.Board, c.Card.Id, c.MultiverseId, c.Quantity)
+		if err != nil {
+			return fmt.Errorf("error inserting deck card %s %s", c.Card.Id, err)
+		}
+	}
+	return nil
+}
+
+// CreateDeck saves a new deck and returns it with a fresh id
+func (s *deckStore) CreateDeck(ctx context.Context, d Deck) (Deck, error) {
+	id, err := newDeckID()
+	if err != nil {
+		return d, err
+	}
+	d.Id = id
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return d, err
+	}
+	if _, err := tx.Exec(queryInsertDeck, d.Id, d.Name, d.Format); err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	if err := s.insertCards(tx, d); err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	if err := tx.Commit(); err != nil {
+		return d, err
+	}
+	return s.GetDeck(ctx, d.Id)
+}
+
+// UpdateDeck replaces the name, format and cards of an existing deck
+func (s *deckStore) UpdateDeck(ctx context.Context, d Deck) (Deck, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return d, err
+	}
+	res, err := tx.Exec(queryUpdateDeck, d.Name, d.Format, d.Id)
+	if err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		tx.Rollback()
+		return d, ErrDeckNotFound
+	}
+	if _, err := tx.Exec(queryDeleteDeckCards, d.Id); err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	if err := s.insertCards(tx, d); err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	if err := tx.Commit(); err != nil {
+		return d, err
+	}
+	return s.GetDeck(ctx, d.Id)
+}
+
+func (s *deckStore) DeleteDeck(ctx context.Context, id string) error {
+	res, err := s.db.ExecC(ctx, queryDeleteDeck, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrDeckNotFound
+	}
+	return nil
+}
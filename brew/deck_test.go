@@ -0,0 +1,50 @@
+package brew
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFillDeckCards(t *testing.T) {
+	bolt := Card{Id: "lightning-bolt", Name: "Lightning Bolt"}
+	fire := Card{Id: "fire-ice", Name: "Fire // Ice", Faces: []Face{{Name: "Fire"}, {Name: "Ice"}}}
+
+	refs := []DeckCard{
+		{Quantity: 4, Board: BoardMain, MultiverseId: 209, Card: Card{Id: "lightning-bolt"}},
+		{Quantity: 2, Board: BoardMain, Card: Card{Id: "fire"}},
+		{Quantity: 3, Board: BoardSide, MultiverseId: 1234, Card: Card{Id: "renamed-card"}},
+		{Quantity: 1, Board: BoardSide, Card: Card{Id: "lightning-bolt"}},
+	}
+
+	found, missing := fillDeckCards(refs, []Card{bolt, fire})
+	expected := []DeckCard{
+		{Quantity: 4, Board: BoardMain, MultiverseId: 209, Card: bolt},
+		{Quantity: 2, Board: BoardMain, Card: fire},
+		{Quantity: 1, Board: BoardSide, Card: bolt},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %+v not %+v", expected, found)
+	}
+	expectedMissing := []DeckCardRef{
+		{Quantity: 3, Board: BoardSide, Id: "renamed-card", MultiverseId: 1234},
+	}
+	if !reflect.DeepEqual(missing, expectedMissing) {
+		t.Errorf("Expected %+v not %+v", expectedMissing, missing)
+	}
+}
+
+func TestDeckCardEdition(t *testing.T) {
+	card := Card{Editions: []Edition{{MultiverseId: 205979}, {MultiverseId: 209}}}
+
+	d := DeckCard{Card: card}
+	if e := d.Edition(); e == nil || e.MultiverseId != 205979 {
+		t.Errorf("Expected the first edition, not %+v", e)
+	}
+	d.MultiverseId = 209
+	if e := d.Edition(); e == nil || e.MultiverseId != 209 {
+		t.Errorf("Expected the chosen edition, not %+v", e)
+	}
+	if e := (&DeckCard{}).Edition(); e != nil {
+		t.Errorf("Expected no edition, not %+v", e)
+	}
+}
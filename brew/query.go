@@ -0,0 +1,243 @@
+package brew
+
+import (
+	"strconv"
+	"strings"
+)
+
+var comparisonOps = map[string]string{
+	"eq":  "=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// Power and toughness are stored as text and can contain values such as *,
+// 1+* or X. Any leading number is used for comparisons and variable values
+// count as zero. Cards without a value never match.
+func numericText(column string) string {
+	return "(CASE WHEN " + column + " = '' THEN NULL ELSE " +
+		"COALESCE(substring(" + column + ` from '^[-+]?[0-9]*\.?[0-9]+')::numeric, 0) END)`
+}
+
+// Only planeswalkers have a loyalty value
+const loyalty = "(CASE WHEN 'planeswalker' = ANY(types) THEN loyalty END)"
+
+// Rank a card by the highest rarity it has been printed at
+const rarityRank = `(CASE
+  WHEN rarities && '{mythic}' THEN 4
+  WHEN rarities && '{rare}' THEN 3
+  WHEN rarities && '{uncommon}' THEN 2
+  WHEN rarities && '{common}' THEN 1
+  ELSE 0 END)`
+
+// The lowest median price from the latest snapshot of each edition
+const lowestPrice = `(SELECT min(latest.median) FROM (
+  SELECT DISTINCT ON (multiverse_id) median FROM prices
+  WHERE multiverse_id = ANY(cards.mids) AND NOT foil
+  ORDER BY multiverse_id, created DESC) latest)`
+
+type sortColumn struct {
+	expr string
+	kind string
+}
+
+var sortColumns = map[string]sortColumn{
+	"name":      {"name", "text"},
+	"cmc":       {"cmc", "numeric"},
+	"power":     {numericText("power"), "numeric"},
+	"toughness": {numericText("toughness"), "numeric"},
+	"rarity":    {rarityRank, "numeric"},
+	"released":  {"released", "date"},
+	"price":     {lowestPrice, "numeric"},
+}
+
+// sortKey returns the sort column and direction for a search, using the
+// defaults when none are given. Sorting by relevance requires a text search
+// and puts the best matches first.
+func sortKey(s Search) (string, string) {
+	sort, order := s.Sort, s.Order
+	if _, ok := sortColumns[sort]; !ok && !(sort == "relevance" && len(s.Text) > 0) {
+		sort = "name"
+	}
+	if order == "" && sort == "relevance" {
+		order = "desc"
+	}
+	if order != "desc" {
+		order = "asc"
+	}
+	return sort, order
+}
+
+// query builds a parameterized WHERE clause from a Search
+type query struct {
+	args []interface{}
+}
+
+func (q *query) param(value interface{}) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *query) array(clauses []string, column string, values []string, match Match) []string {
+	if len(values) == 0 {
+		return clauses
+	}
+	p := q.param(sarray(values))
+	switch match {
+	case MatchAll:
+		return append(clauses, column+" @> "+p)
+	case MatchExact:
+		return append(clauses, "("+column+" @> "+p+" AND "+column+" <@ "+p+")")
+	case MatchSubset:
+		return append(clauses, column+" <@ "+p)
+	case MatchNone:
+		return append(clauses, "NOT "+column+" && "+p)
+	}
+	return append(clauses, column+" && "+p)
+}
+
+func (q *query) like(clauses []string, column string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return clauses
+	}
+	return append(clauses, column+" ILIKE ANY ("+q.param(sarray(patterns))+")")
+}
+
+func (q *query) compare(clauses []string, column string, cs []Comparison) []string {
+	for _, c := range cs {
+		op, ok := comparisonOps[c.Op]
+		if !ok {
+			op = "="
+		}
+		clauses = append(clauses, column+" "+op+" "+q.param(c.Value))
+	}
+	return clauses
+}
+
+func (q *query) where(s Search) string {
+	clauses := []string{}
+
+	if s.IncludeMulticolor {
+		clauses = append(clauses, "multicolor = "+q.param(s.Multicolor))
+	}
+
+	clauses = q.array(clauses, "rarities", s.Rarities, s.RarityMatch)
+	clauses = q.array(clauses, "types", s.Types, s.TypeMatch)
+	clauses = q.array(clauses, "supertypes", s.Supertypes, s.SupertypeMatch)
+	clauses = q.array(clauses, "colors", s.Colors, s.ColorMatch)
+	clauses = q.array(clauses, "color_identity", s.Identity, MatchSubset)
+	clauses = q.array(clauses, "subtypes", s.Subtypes, s.SubtypeMatch)
+	clauses = q.array(clauses, "formats", s.Formats, s.FormatMatch)
+	clauses = q.array(clauses, "status", s.Status, MatchAny)
+	clauses = q.array(clauses, "mids", s.MultiverseIDs, MatchAny)
+	clauses = q.array(clauses, "sets", s.Sets, s.SetMatch)
+	clauses = q.like(clauses, "name", s.Names)
+	clauses = q.like(clauses, "rules", s.Rules)
+	for _, text := range s.Text {
+		clauses = append(clauses, "search @@ websearch_to_tsquery('english', "+q.param(text)+")")
+	}
+	clauses = q.compare(clauses, "cmc", s.CMC)
+	clauses = q.compare(clauses, numericText("power"), s.Power)
+	clauses = q.compare(clauses, numericText("toughness"), s.Toughness)
+	clauses = q.compare(clauses, loyalty, s.Loyalty)
+
+	for _, sub := range s.And {
+		clauses = append(clauses, "("+q.where(sub)+")")
+	}
+
+	if len(s.Or) > 0 {
+		alternatives := []string{}
+		for _, sub := range s.Or {
+			alternatives = append(alternatives, "("+q.where(sub)+")")
+		}
+		clauses = append(clauses, "("+strings.Join(alternatives, " OR ")+")")
+	}
+
+	for _, sub := range s.Not {
+		clauses = append(clauses, "NOT ("+q.where(sub)+")")
+	}
+
+	if len(clauses) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+func (q *query) sortColumn(s Search) (sortColumn, string) {
+	sort, order := sortKey(s)
+	if sort == "relevance" {
+		terms := q.param(strings.Join(s.Text, " "))
+		return sortColumn{"ts_rank(search, websearch_to_tsquery('english', " + terms + "))", "real"}, order
+	}
+	return sortColumns[sort], order
+}
+
+// Cards without a value for the sort column always come last. Ties are
+// broken by id so that paging through results is stable.
+func (q *query) order(s Search) string {
+	column, order := q.sortColumn(s)
+	direction := strings.ToUpper(order)
+	return column.expr + " " + direction + " NULLS LAST, id " + direction
+}
+
+// after restricts a search to the cards that follow its cursor, using the
+// same ordering as order
+func (q *query) after(s Search) string {
+	if s.Cursor == nil {
+		return "TRUE"
+	}
+	column, order := q.sortColumn(s)
+
+	op := ">"
+	if order == "desc" {
+		op = "<"
+	}
+
+	id := q.param(s.Cursor.Id)
+	if s.Cursor.Value == nil {
+		return "(" + column.expr + " IS NULL AND id " + op + " " + id + ")"
+	}
+
+	value := q.param(*s.Cursor.Value) + "::" + column.kind
+	return "(" + column.expr + " " + op + " " + value +
+		" OR (" + column.expr + " = " + value + " AND id " + op + " " + id + ")" +
+		" OR " + column.expr + " IS NULL)"
+}
+
+func (q *query) setWhere(s SetSearch) string {
+	clauses := []string{}
+	if len(s.Types) > 0 {
+		clauses = append(clauses, "type = ANY ("+q.param(quotedArray(s.Types))+")")
+	}
+	if len(s.Borders) > 0 {
+		clauses = append(clauses, "border = ANY ("+q.param(quotedArray(s.Borders))+")")
+	}
+	if len(s.Blocks) > 0 {
+		clauses = append(clauses, "lower(block) = ANY ("+q.param(quotedArray(s.Blocks))+")")
+	}
+	if s.From != "" {
+		clauses = append(clauses, "released >= "+q.param(s.From)+"::date")
+	}
+	if s.To != "" {
+		clauses = append(clauses, "released <= "+q.param(s.To)+"::date")
+	}
+	if len(clauses) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// Sets are sorted by name unless sorted by release date, in which case sets
+// without a date come last
+func setOrder(s SetSearch) string {
+	direction := "ASC"
+	if s.Order == "desc" {
+		direction = "DESC"
+	}
+	if s.Sort == "released" {
+		return "released " + direction + " NULLS LAST, name " + direction
+	}
+	return "name " + direction
+}
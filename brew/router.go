@@ -8,7 +8,8 @@ import (
 )
 
 type router struct {
-	cfg *config.Config
+	cfg    *config.Config
+	stores []StoreLinker
 }
 
 func base(host string) string {
@@ -42,3 +43,7 @@ func (r router) EditionImageURL(id int) string {
 func (r router) EditionHtmlURL(id int) string {
 	return fmt.Sprintf("%s/mtg/cards/%d", base(r.cfg.HostWeb), id)
 }
+
+func (r router) DeckURL(id string) string {
+	return fmt.Sprintf("%s/mtg/decks/%s", base(r.cfg.HostAPI), id)
+}
@@ -1,6 +1,7 @@
 package brew
 
 import (
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -10,10 +11,17 @@ import (
 
 type Reader interface {
 	GetCards(context.Context, Search) ([]Card, error)
+	GetCardPage(context.Context, Search) ([]Card, *Cursor, error)
+	CountCards(context.Context, Search) (int, error)
 	GetCardsByName(context.Context, string) ([]Card, error)
+	ResolveCardName(context.Context, string) (Resolution, error)
 	GetCard(context.Context, string) (Card, error)
+	GetCardRedirect(context.Context, string) (string, error)
+	GetRulings(context.Context, []string) (map[string][]Ruling, error)
+	GetPriceHistory(context.Context, PriceHistorySearch) (PriceHistory, error)
+	GetCardCollection(context.Context, []CardIdentifier) ([]Card, []CardIdentifier, error)
 	GetRandomCardID(context.Context) (string, error)
-	GetSets(context.Context) ([]Set, error)
+	GetSets(context.Context, SetSearch) ([]Set, error)
 	GetSet(context.Context, string) (Set, error)
 	GetColors(context.Context) ([]string, error)
 	GetSupertypes(context.Context) ([]string, error)
@@ -21,6 +29,14 @@ type Reader interface {
 	GetTypes(context.Context) ([]string, error)
 }
 
+var slugPunctuation = regexp.MustCompile(`[,.'"?:()]`)
+
+// Slug turns a card name into its id
+func Slug(name string) string {
+	d := strings.Replace(strings.ToLower(name), " ", "-", -1)
+	return slugPunctuation.ReplaceAllLiteralString(d, "")
+}
+
 func toUniqueLower(things []string) []string {
 	seen := map[string]bool{}
 	sorted := []string{}
@@ -36,6 +52,7 @@ func toUniqueLower(things []string) []string {
 
 type Search struct {
 	Colors            []string
+	Identity          []string
 	Formats           []string
 	IncludeMulticolor bool
 	Multicolor        bool
@@ -47,10 +64,68 @@ type Search struct {
 	Subtypes          []string
 	Supertypes        []string
 	Rules             []string
+	Text              []string
 	Types             []string
+	CMC               []Comparison
+	Power             []Comparison
+	Toughness         []Comparison
+	Loyalty           []Comparison
+	Sort              string
+	Order             string
+	Cursor            *Cursor
 	Limit             int
 	Offset            int
 	Page              int
+
+	// Match modes for the array filters above. The zero value is MatchAny.
+	ColorMatch     Match
+	FormatMatch    Match
+	RarityMatch    Match
+	SetMatch       Match
+	SubtypeMatch   Match
+	SupertypeMatch Match
+	TypeMatch      Match
+
+	// Nested searches allow arbitrary boolean grouping. A card must match
+	// every search in And, at least one search in Or and none in Not.
+	And []Search
+	Or  []Search
+	Not []Search
+}
+
+// A SetSearch filters sets. From and To are inclusive release dates in
+// YYYY-MM-DD form.
+type SetSearch struct {
+	Types   []string
+	Borders []string
+	Blocks  []string
+	From    string
+	To      string
+	Sort    string
+	Order   string
+}
+
+// A Match controls how an array filter compares its values against a card
+type Match string
+
+const (
+	// The card has at least one of the values
+	MatchAny Match = "any"
+	// The card has every value
+	MatchAll Match = "all"
+	// The card has every value and nothing else
+	MatchExact Match = "exact"
+	// The card has nothing but the values
+	MatchSubset Match = "subset"
+	// The card has none of the values
+	MatchNone Match = "none"
+)
+
+// A Comparison restricts a numeric card attribute. Op is one of eq, gt,
+// gte, lt or lte.
+type Comparison struct {
+	Op    string
+	Value int
 }
 
 type Card struct {
@@ -58,10 +133,12 @@ type Card struct {
 	Id            string            `json:"id"`
 	Href          string            `json:"url,omitempty"`
 	StoreUrl      string            `json:"store_url"`
+	StoreUrls     []StoreURL        `json:"store_urls,omitempty"`
 	Types         []string          `json:"types,omitempty"`
 	Supertypes    []string          `json:"supertypes,omitempty"`
 	Subtypes      []string          `json:"subtypes,omitempty"`
 	Colors        []string          `json:"colors,omitempty"`
+	ColorIdentity []string          `json:"color_identity,omitempty"`
 	ConvertedCost int               `json:"cmc"`
 	ManaCost      string            `json:"cost"`
 	Text          string            `json:"text"`
@@ -69,7 +146,10 @@ type Card struct {
 	Toughness     string            `json:"toughness,omitempty"`
 	Loyalty       int               `json:"loyalty,omitempty"`
 	FormatMap     map[string]string `json:"formats"`
+	Faces         []Face            `json:"faces,omitempty"`
 	Editions      []Edition         `json:"editions,omitempty"`
+	Rulings       []Ruling          `json:"rulings,omitempty"`
+	Released      string            `json:"-"`
 }
 
 func (c *Card) Sets() []string {
@@ -110,10 +190,26 @@ func (c *Card) MultiverseIds() []string {
 	r := []string{}
 	for _, e := range c.Editions {
 		r = append(r, strconv.Itoa(e.MultiverseId))
+		for _, id := range e.FaceIds {
+			r = append(r, strconv.Itoa(id))
+		}
 	}
 	return toUniqueLower(r)
 }
 
+// Flavors returns the unique flavor text of every edition
+func (c *Card) Flavors() string {
+	seen := map[string]bool{}
+	flavors := []string{}
+	for _, e := range c.Editions {
+		if e.Flavor != "" && !seen[e.Flavor] {
+			flavors = append(flavors, e.Flavor)
+			seen[e.Flavor] = true
+		}
+	}
+	return strings.Join(flavors, "\n")
+}
+
 func (c *Card) Multicolor() bool {
 	return len(c.Colors) > 1
 }
@@ -121,7 +217,13 @@ func (c *Card) Multicolor() bool {
 // Don't expose this
 func (c *Card) Fill(r router) {
 	c.Href = r.CardURL(c.Id)
-	c.StoreUrl = TCGCardURL(c)
+	c.StoreUrls = nil
+	for _, s := range r.stores {
+		c.StoreUrls = append(c.StoreUrls, StoreURL{Store: s.Store(), Href: s.CardURL(c)})
+	}
+	if len(c.StoreUrls) > 0 {
+		c.StoreUrl = c.StoreUrls[0].Href
+	}
 
 	for i, _ := range c.Editions {
 		e := &c.Editions[i]
@@ -129,39 +231,160 @@ func (c *Card) Fill(r router) {
 		e.SetUrl = r.SetURL(e.SetId)
 		e.ImageUrl = r.EditionImageURL(e.MultiverseId)
 		e.HTMLUrl = r.EditionHtmlURL(e.MultiverseId)
-		e.StoreUrl = TCGEditionURL(c, e)
-		e.Price = &Price{
-			Low:     0,
-			Average: 0,
-			High:    0,
+		e.StoreUrls = nil
+		for _, s := range r.stores {
+			e.StoreUrls = append(e.StoreUrls, StoreURL{Store: s.Store(), Href: s.EditionURL(c, e)})
+		}
+		if len(e.StoreUrls) > 0 {
+			e.StoreUrl = e.StoreUrls[0].Href
+		}
+	}
+}
+
+// A Face is one side or half of a split, flip, double-faced or meld card
+type Face struct {
+	Name      string   `json:"name"`
+	ManaCost  string   `json:"cost"`
+	Type      string   `json:"type"`
+	Colors    []string `json:"colors,omitempty"`
+	Text      string   `json:"text"`
+	Power     string   `json:"power,omitempty"`
+	Toughness string   `json:"toughness,omitempty"`
+	Loyalty   int      `json:"loyalty,omitempty"`
+}
+
+type Ruling struct {
+	Date string `json:"date"`
+	Text string `json:"text"`
+}
+
+// A Resolution is the card that best matches a possibly misspelled name.
+// Scores range from 0 to 1, where 1 is an exact match.
+type Resolution struct {
+	Card         Card        `json:"card"`
+	Score        float64     `json:"score"`
+	Alternatives []CardMatch `json:"alternatives"`
+}
+
+type CardMatch struct {
+	Name  string  `json:"name"`
+	Id    string  `json:"id"`
+	Href  string  `json:"url"`
+	Score float64 `json:"score"`
+}
+
+// A CardIdentifier refers to a card by id, multiverse id, exact name or set
+// and collector number. Only one of these should be used.
+type CardIdentifier struct {
+	Id           string `json:"id,omitempty"`
+	MultiverseId int    `json:"multiverse_id,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Set          string `json:"set,omitempty"`
+	Number       string `json:"number,omitempty"`
+}
+
+// Matches reports whether the identifier refers to the given card
+func (i CardIdentifier) Matches(c *Card) bool {
+	switch {
+	case i.Id != "":
+		if i.Id == c.Id {
+			return true
+		}
+		for _, f := range c.Faces {
+			if Slug(f.Name) == i.Id {
+				return true
+			}
+		}
+		return false
+	case i.Name != "":
+		if strings.EqualFold(i.Name, c.Name) {
+			return true
 		}
+		for _, f := range c.Faces {
+			if strings.EqualFold(i.Name, f.Name) {
+				return true
+			}
+		}
+		return false
 	}
+	for _, e := range c.Editions {
+		if i.MultiverseId != 0 && e.HasMultiverseId(i.MultiverseId) {
+			return true
+		}
+		if i.Set != "" && strings.EqualFold(i.Set, e.SetId) && i.Number == e.Number {
+			return true
+		}
+	}
+	return false
 }
 
 type Edition struct {
-	Set          string `json:"set"`
-	SetId        string `json:"set_id"`
-	CardId       string `json:"-"`
-	Watermark    string `json:"watermark,omitempty"`
-	Rarity       string `json:"rarity"`
-	Border       string `json:"-"`
-	Artist       string `json:"artist"`
-	MultiverseId int    `json:"multiverse_id"`
-	Flavor       string `json:"flavor,omitempty"`
-	Number       string `json:"number"`
-	Layout       string `json:"layout"`
-	Price        *Price `json:"price,omitempty"`
-	Href         string `json:"url,omitempty"`
-	ImageUrl     string `json:"image_url,omitempty"`
-	SetUrl       string `json:"set_url,omitempty"`
-	StoreUrl     string `json:"store_url"`
-	HTMLUrl      string `json:"html_url"`
+	Set          string     `json:"set"`
+	SetId        string     `json:"set_id"`
+	CardId       string     `json:"-"`
+	Watermark    string     `json:"watermark,omitempty"`
+	Rarity       string     `json:"rarity"`
+	Border       string     `json:"-"`
+	Artist       string     `json:"artist"`
+	MultiverseId int        `json:"multiverse_id"`
+	FaceIds      []int      `json:"face_multiverse_ids,omitempty"`
+	Flavor       string     `json:"flavor,omitempty"`
+	Number       string     `json:"number"`
+	Layout       string     `json:"layout"`
+	Price        *Price     `json:"price,omitempty"`
+	FoilPrice    *Price     `json:"foil_price,omitempty"`
+	Href         string     `json:"url,omitempty"`
+	ImageUrl     string     `json:"image_url,omitempty"`
+	SetUrl       string     `json:"set_url,omitempty"`
+	StoreUrl     string     `json:"store_url"`
+	StoreUrls    []StoreURL `json:"store_urls,omitempty"`
+	HTMLUrl      string     `json:"html_url"`
+}
+
+// HasMultiverseId reports whether the edition or one of its other faces has
+// the given multiverse id
+func (e *Edition) HasMultiverseId(id int) bool {
+	if e.MultiverseId == id {
+		return true
+	}
+	for _, f := range e.FaceIds {
+		if f == id {
+			return true
+		}
+	}
+	return false
 }
 
+// A Price is the latest snapshot of an edition's price, in cents
 type Price struct {
-	Low     int `json:"low"`
-	Average int `json:"median"`
-	High    int `json:"high"`
+	Low       int    `json:"low"`
+	Average   int    `json:"median"`
+	High      int    `json:"high"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// A PriceHistorySearch selects the price snapshots of the given editions,
+// grouped by day or week. From and To are inclusive dates in YYYY-MM-DD form.
+type PriceHistorySearch struct {
+	MultiverseIDs []string
+	From          string
+	To            string
+	Interval      string
+}
+
+// A PriceHistory is a series of prices for one or more editions. Each point
+// has the lowest low, median median and highest high price of its period.
+type PriceHistory struct {
+	Interval   string       `json:"interval"`
+	Prices     []PricePoint `json:"prices"`
+	FoilPrices []PricePoint `json:"foil_prices"`
+}
+
+type PricePoint struct {
+	Date    string `json:"date"`
+	Low     int    `json:"low"`
+	Average int    `json:"median"`
+	High    int    `json:"high"`
 }
 
 type Set struct {
@@ -169,6 +392,9 @@ type Set struct {
 	Name       string `json:"name"`
 	Border     string `json:"border"`
 	Type       string `json:"type"`
+	Released   string `json:"released,omitempty"`
+	Block      string `json:"block,omitempty"`
+	Size       int    `json:"size"`
 	Href       string `json:"url"`
 	CardsUrl   string `json:"cards_url"`
 	PriceGuide string `json:"-"`
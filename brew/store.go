@@ -0,0 +1,78 @@
+package brew
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/kyleconroy/deckbrew/config"
+)
+
+// A StoreLinker builds links to a store's product pages
+type StoreLinker interface {
+	Store() string
+	CardURL(c *Card) string
+	EditionURL(c *Card, e *Edition) string
+}
+
+// A StoreURL is a link to a card or edition in a single store
+type StoreURL struct {
+	Store string `json:"store"`
+	Href  string `json:"url"`
+}
+
+// stores holds every store that can be enabled from the configuration. Each
+// is created with its partner code.
+var stores = map[string]func(partner string, tcg TCGMap) StoreLinker{
+	"tcgplayer": func(partner string, tcg TCGMap) StoreLinker {
+		return TCGPlayer{Map: tcg, Partner: partner}
+	},
+	"cardkingdom": func(partner string, tcg TCGMap) StoreLinker {
+		return CardKingdom{Partner: partner}
+	},
+}
+
+// storeLinkers returns the configured stores in order. The first store is
+// used for the single store_url fields.
+func storeLinkers(cfg *config.Config, tcg TCGMap) ([]StoreLinker, error) {
+	names := cfg.Stores
+	if len(names) == 0 {
+		names = []string{"tcgplayer"}
+	}
+	linkers := []StoreLinker{}
+	for _, name := range names {
+		create, ok := stores[name]
+		if !ok {
+			return linkers, fmt.Errorf("The store '%s' is not recognized", name)
+		}
+		partner, ok := cfg.StorePartners[name]
+		if !ok && name == "tcgplayer" {
+			partner = "DECKBREW"
+		}
+		linkers = append(linkers, create(partner, tcg))
+	}
+	return linkers, nil
+}
+
+// CardKingdom links cards to a name search on Card Kingdom, which doesn't
+// have predictable product URLs
+type CardKingdom struct {
+	Partner string
+}
+
+func (k CardKingdom) Store() string {
+	return "cardkingdom"
+}
+
+func (k CardKingdom) CardURL(c *Card) string {
+	query := url.Values{}
+	query.Set("search", "header")
+	query.Set("filter[name]", c.Name)
+	if k.Partner != "" {
+		query.Set("partner", k.Partner)
+	}
+	return "https://www.cardkingdom.com/catalog/search?" + query.Encode()
+}
+
+func (k CardKingdom) EditionURL(c *Card, e *Edition) string {
+	return k.CardURL(c)
+}
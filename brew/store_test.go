@@ -0,0 +1,71 @@
+package brew
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyleconroy/deckbrew/config"
+)
+
+func TestStoreLinkers(t *testing.T) {
+	tcg := TCGMap{}
+	for _, test := range []struct {
+		stores   []string
+		partners map[string]string
+		expected []StoreLinker
+	}{
+		{nil, nil, []StoreLinker{TCGPlayer{Map: tcg, Partner: "DECKBREW"}}},
+		{[]string{"tcgplayer"}, map[string]string{"tcgplayer": "ABC"},
+			[]StoreLinker{TCGPlayer{Map: tcg, Partner: "ABC"}}},
+		{[]string{"tcgplayer"}, map[string]string{"tcgplayer": ""},
+			[]StoreLinker{TCGPlayer{Map: tcg, Partner: ""}}},
+		{[]string{"cardkingdom", "tcgplayer"}, map[string]string{"cardkingdom": "XY"},
+			[]StoreLinker{CardKingdom{Partner: "XY"}, TCGPlayer{Map: tcg, Partner: "DECKBREW"}}},
+		{[]string{"cardkingdom"}, nil, []StoreLinker{CardKingdom{}}},
+	} {
+		cfg := &config.Config{Stores: test.stores, StorePartners: test.partners}
+		linkers, err := storeLinkers(cfg, tcg)
+		if err != nil {
+			t.Errorf("%v: %s", test.stores, err)
+			continue
+		}
+		if !reflect.DeepEqual(linkers, test.expected) {
+			t.Errorf("%v: expected %+v not %+v", test.stores, test.expected, linkers)
+		}
+	}
+
+	cfg := &config.Config{Stores: []string{"tcgplayer", "starcity"}}
+	if _, err := storeLinkers(cfg, tcg); err == nil {
+		t.Error("Expected an unknown store to return an error")
+	}
+}
+
+func TestCardKingdomURL(t *testing.T) {
+	c := &Card{Name: "Jace, the Mind Sculptor"}
+	e := &Edition{SetId: "WWK", MultiverseId: 195297}
+
+	for partner, expected := range map[string]string{
+		"":   "https://www.cardkingdom.com/catalog/search?filter%5Bname%5D=Jace%2C+the+Mind+Sculptor&search=header",
+		"XY": "https://www.cardkingdom.com/catalog/search?filter%5Bname%5D=Jace%2C+the+Mind+Sculptor&partner=XY&search=header",
+	} {
+		k := CardKingdom{Partner: partner}
+		if link := k.CardURL(c); link != expected {
+			t.Errorf("Expected %s not %s", expected, link)
+		}
+		if link := k.EditionURL(c, e); link != expected {
+			t.Errorf("Expected the edition to link to %s not %s", expected, link)
+		}
+	}
+}
+
+func TestTCGPlayerCardURL(t *testing.T) {
+	c := &Card{Name: "Lightning Bolt", Editions: []Edition{{SetId: "M10"}, {SetId: "LEA"}}}
+	for partner, expected := range map[string]string{
+		"DECKBREW": "http://store.tcgplayer.com/magic/product/show?partner=DECKBREW&ProductName=lightning-bolt",
+		"":         "http://store.tcgplayer.com/magic/product/show?ProductName=lightning-bolt",
+	} {
+		if link := (TCGPlayer{Partner: partner}).CardURL(c); link != expected {
+			t.Errorf("Expected %s not %s", expected, link)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package brew
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+
+	"golang.org/x/net/context"
+	"stackmachine.com/cql"
 )
 
 func ReplaceUnicode(name string) string {
@@ -32,171 +36,112 @@ func TCGSlug(name string) string {
 	return ReplaceUnicode(re.ReplaceAllLiteralString(d, ""))
 }
 
-func TCGCardURL(c *Card) string {
+// A TCGMap holds the names TCGplayer uses for editions and sets whose names
+// differ from the ones on Gatherer
+type TCGMap struct {
+	Names map[int]string
+	Sets  map[string]string
+}
+
+const queryTCGNames = `
+SELECT multiverse_id, name FROM tcg_names
+`
+
+const queryTCGSets = `
+SELECT set_id, name FROM tcg_sets
+`
+
+// LoadTCGMap reads the TCGplayer name and set mappings from the database
+func LoadTCGMap(ctx context.Context, db *cql.DB) (TCGMap, error) {
+	m := TCGMap{Names: map[int]string{}, Sets: map[string]string{}}
+
+	rows, err := db.QueryC(ctx, queryTCGNames)
+	if err != nil {
+		return m, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return m, err
+		}
+		m.Names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return m, err
+	}
+
+	rows, err = db.QueryC(ctx, queryTCGSets)
+	if err != nil {
+		return m, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return m, err
+		}
+		m.Sets[id] = name
+	}
+	return m, rows.Err()
+}
+
+// TCGPlayer links cards to the TCGplayer store
+type TCGPlayer struct {
+	Map     TCGMap
+	Partner string
+}
+
+func (t TCGPlayer) Store() string {
+	return "tcgplayer"
+}
+
+func (t TCGPlayer) partner() string {
+	if t.Partner == "" {
+		return ""
+	}
+	return "partner=" + url.QueryEscape(t.Partner)
+}
+
+func (t TCGPlayer) CardURL(c *Card) string {
 	if len(c.Editions) == 1 {
-		return TCGEditionURL(c, &c.Editions[0])
-	} else {
-		return "http://store.tcgplayer.com/magic/product/show?partner=DECKBREW&ProductName=" + TCGSlug(c.Name)
+		return t.EditionURL(c, &c.Editions[0])
+	}
+	query := "ProductName=" + TCGSlug(c.Name)
+	if p := t.partner(); p != "" {
+		query = p + "&" + query
 	}
+	return "http://store.tcgplayer.com/magic/product/show?" + query
 }
 
-func TCGEditionURL(c *Card, e *Edition) string {
-	set := TCGSlug(TCGSet(e.SetId, e.Set))
-	id := TCGSlug(TCGName(c.Name, e.MultiverseId))
-	return fmt.Sprintf("http://store.tcgplayer.com/magic/%s/%s?partner=DECKBREW", set, id)
+func (t TCGPlayer) EditionURL(c *Card, e *Edition) string {
+	set := TCGSlug(t.Map.Set(e.SetId, e.Set))
+	id := TCGSlug(t.Map.Name(c.Name, e.MultiverseId))
+	link := fmt.Sprintf("http://store.tcgplayer.com/magic/%s/%s", set, id)
+	if p := t.partner(); p != "" {
+		link += "?" + p
+	}
+	return link
 }
 
 func NormalizeName(name string) string {
 	return strings.ToLower(ReplaceUnicode(strings.TrimSpace(name)))
 }
 
-func TCGName(name string, id int) string {
-	translation := TranslateID(id)
-	if translation != "" {
+// Name returns the normalized TCGplayer name of a card's edition
+func (m TCGMap) Name(name string, id int) string {
+	if translation, ok := m.Names[id]; ok {
 		return NormalizeName(translation)
 	} else {
 		return NormalizeName(name)
 	}
 }
 
-func TranslateID(id int) string {
-	switch id {
-	case 1071:
-		return "Mishra's Factory (Fall)"
-	case 1072:
-		return "Mishra's Factory (Spring)"
-	case 1073:
-		return "Mishra's Factory (Summer)"
-	case 1074:
-		return "Mishra's Factory (Winter)"
-	case 1076:
-		return "Strip Mine (Even Horizon)"
-	case 1077:
-		return "Strip Mine (Uneven Horizon)"
-	case 1078:
-		return "Strip Mine (No Horizon)"
-	case 1079:
-		return "Strip Mine (Tower)"
-	case 1080, 2888:
-		return "Urza's Mine (Clawed Sphere)"
-	case 1081, 2889:
-		return "Urza's Mine (Mouth)"
-	case 1082, 2890:
-		return "Urza's Mine (Pulley)"
-	case 1083, 2891:
-		return "Urza's Mine (Tower)"
-	case 1084, 2892:
-		return "Urza's Power Plant (Bug)"
-	case 1085, 2893:
-		return "Urza's Power Plant (Columns)"
-	case 1086, 2894:
-		return "Urza's Power Plant (Sphere)"
-	case 1087, 2895:
-		return "Urza's Power Plant (Rock in Pot)"
-	case 1088, 2896:
-		return "Urza's Tower (Forest)"
-	case 1089, 2897:
-		return "Urza's Tower (Mountains)"
-	case 2898, 1090:
-		return "Urza's Tower (Plains)"
-	case 1091, 2899:
-		return "Urza's Tower (Shore)"
-	case 2969:
-		return "Hungry Mist [Version 1]"
-	case 2970:
-		return "Hungry Mist [Version 2]"
-	case 3021:
-		return "Mesa Falcon [Version 1]"
-	case 3022:
-		return "Mesa Falcon [Version 2]"
-	case 3207:
-		return "Reinforcements (Version 2)"
-	case 4979:
-		return "Pegasus Token"
-	case 5472:
-		return "Soldier Token"
-	case 5503:
-		return "Goblin Token"
-	case 5560:
-		return "Sheep Token"
-	case 5601:
-		return "Zombie Token"
-	case 5607:
-		return "Squirrel Token"
-	case 9757:
-		return "The Ultimate Nightmare of Wizards of the Coast Cu"
-	case 9780:
-		return "B.F.M. (Big Furry Monster Left)"
-	case 9844:
-		return "B.F.M. (Big Furry Monster Right)"
-	case 74237:
-		return "Our Market Research..."
-	case 78968:
-		return "Brothers Yamazaki (160a Sword)"
-	case 85106:
-		return "Brothers Yamazaki (160b Pike)"
-	case 209163:
-		return "Hornet Token"
-	case 386322:
-		return "Goblin Token"
-	}
-	return ""
-}
-
-func TCGSet(setId, set string) string {
-	switch setId {
-	case "10E":
-		return "10th Edition"
-	case "9ED":
-		return "9th Edition"
-	case "8ED":
-		return "8th Edition"
-	case "7ED":
-		return "7th Edition"
-	case "M15":
-		return "Magic 2015 (M15)"
-	case "M14":
-		return "Magic 2014 (M14)"
-	case "M13":
-		return "Magic 2013 (M13)"
-	case "M12":
-		return "Magic 2012 (M12)"
-	case "M11":
-		return "Magic 2011 (M11)"
-	case "M10":
-		return "Magic 2010 (M10)"
-	case "CMD":
-		return "Commander"
-	case "HHO":
-		return "Special Occasion"
-	case "RAV":
-		return "Ravnica"
-	case "DDG":
-		return "Duel Decks: Knights vs Dragons"
-	case "DDL":
-		return "Duel Decks: Heroes vs. Monsters"
-	case "PC2":
-		return "Planechase 2012"
-	case "C13":
-		return "Commander 2013"
-	case "C14":
-		return "Commander 2014"
-	case "PD2":
-		return "Premium Deck Series: Fire and Lightning"
-	case "LEB":
-		return "Beta Edition"
-	case "LEA":
-		return "Alpha Edition"
-	case "TSB":
-		return "Timeshifted"
-	case "MD1":
-		return "Magic Modern Event Deck"
-	case "CNS":
-		return "Conspiracy"
-	case "DKM":
-		return "Deckmasters Garfield vs. Finkel"
-	case "KTK":
-		return "Khans of Tarkir"
+// Set returns the TCGplayer name of a set
+func (m TCGMap) Set(setId, set string) string {
+	if name, ok := m.Sets[setId]; ok {
+		return name
 	}
 	return set
 }
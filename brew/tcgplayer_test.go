@@ -0,0 +1,50 @@
+package brew
+
+import "testing"
+
+func TestTCGMap(t *testing.T) {
+	m := TCGMap{
+		Names: map[int]string{370603: "Jace, the Mind Sculptor (Borderless)"},
+		Sets:  map[string]string{"MMA": "Modern Masters"},
+	}
+
+	for _, test := range []struct {
+		name     string
+		id       int
+		expected string
+	}{
+		{"Jace, the Mind Sculptor", 370603, "jace, the mind sculptor (borderless)"},
+		{" Æther Vial ", 39586, "aether vial"},
+	} {
+		if name := m.Name(test.name, test.id); name != test.expected {
+			t.Errorf("Expected %s not %s", test.expected, name)
+		}
+	}
+
+	if set := m.Set("MMA", "Modern Masters 2013"); set != "Modern Masters" {
+		t.Errorf("Expected the set override, not %s", set)
+	}
+	if set := m.Set("M10", "Magic 2010"); set != "Magic 2010" {
+		t.Errorf("Expected the Gatherer set name, not %s", set)
+	}
+	if set := (TCGMap{}).Set("M10", "Magic 2010"); set != "Magic 2010" {
+		t.Errorf("Expected an empty map to fall back to the set name, not %s", set)
+	}
+}
+
+func TestTCGPlayerEditionURL(t *testing.T) {
+	tcg := TCGPlayer{
+		Map: TCGMap{
+			Names: map[int]string{159132: "Aether Vial"},
+			Sets:  map[string]string{"MMA": "Modern Masters"},
+		},
+		Partner: "DECKBREW",
+	}
+	c := &Card{Name: "Æther Vial"}
+	e := &Edition{SetId: "MMA", Set: "Modern Masters 2013", MultiverseId: 159132}
+
+	expected := "http://store.tcgplayer.com/magic/modern-masters/aether-vial?partner=DECKBREW"
+	if link := tcg.EditionURL(c, e); link != expected {
+		t.Errorf("Expected %s not %s", expected, link)
+	}
+}
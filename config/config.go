@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"stackmachine.com/cql"
 )
@@ -14,6 +15,10 @@ type Config struct {
 	HostImage string
 	HostAPI   string
 	HostWeb   string
+
+	// Stores to link cards to, in order, and their partner codes
+	Stores        []string
+	StorePartners map[string]string
 }
 
 func env(key, empty string) string {
@@ -24,6 +29,30 @@ func env(key, empty string) string {
 	return value
 }
 
+func list(key, empty string) []string {
+	values := []string{}
+	for _, value := range strings.Split(env(key, empty), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// partners parses a list of store=code pairs. An empty code turns off the
+// store's default partner code.
+func partners(key string) (map[string]string, error) {
+	codes := map[string]string{}
+	for _, pair := range list(key, "") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s should contain store=code pairs, not %s", key, pair)
+		}
+		codes[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return codes, nil
+}
+
 func FromEnv() (*Config, error) {
 
 	// Configure the database
@@ -37,6 +66,11 @@ func FromEnv() (*Config, error) {
 		return nil, err
 	}
 
+	codes, err := partners("DECKBREW_STORE_PARTNERS")
+	if err != nil {
+		return nil, err
+	}
+
 	port := env("PORT", "3000")
 	return &Config{
 		DB:        db,
@@ -44,5 +78,8 @@ func FromEnv() (*Config, error) {
 		HostImage: env("DECKBREW_IMAGE_HOST", "deckbrew.image:"+port),
 		HostAPI:   env("DECKBREW_API_HOST", "deckbrew.api:"+port),
 		HostWeb:   env("DECKBREW_WEB_HOST", "deckbrew.web:"+port),
+
+		Stores:        list("DECKBREW_STORES", "tcgplayer"),
+		StorePartners: codes,
 	}, nil
 }
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	for value, expected := range map[string][]string{
+		"":                         {"tcgplayer"},
+		"cardkingdom":              {"cardkingdom"},
+		" tcgplayer , cardkingdom": {"tcgplayer", "cardkingdom"},
+		"tcgplayer,,":              {"tcgplayer"},
+	} {
+		os.Setenv("DECKBREW_TEST_STORES", value)
+		if stores := list("DECKBREW_TEST_STORES", "tcgplayer"); !reflect.DeepEqual(stores, expected) {
+			t.Errorf("%q: expected %v not %v", value, expected, stores)
+		}
+	}
+	os.Unsetenv("DECKBREW_TEST_STORES")
+}
+
+func TestPartners(t *testing.T) {
+	defer os.Unsetenv("DECKBREW_TEST_PARTNERS")
+
+	for value, expected := range map[string]map[string]string{
+		"":                                  {},
+		"tcgplayer=ABC":                     {"tcgplayer": "ABC"},
+		" tcgplayer = ABC , cardkingdom=XY": {"tcgplayer": "ABC", "cardkingdom": "XY"},
+		"tcgplayer=":                        {"tcgplayer": ""},
+		"cardkingdom=a=b":                   {"cardkingdom": "a=b"},
+	} {
+		os.Setenv("DECKBREW_TEST_PARTNERS", value)
+		codes, err := partners("DECKBREW_TEST_PARTNERS")
+		if err != nil {
+			t.Errorf("%q: %s", value, err)
+			continue
+		}
+		if !reflect.DeepEqual(codes, expected) {
+			t.Errorf("%q: expected %v not %v", value, expected, codes)
+		}
+	}
+
+	for _, value := range []string{"tcgplayer", "=ABC", "tcgplayer=ABC,cardkingdom"} {
+		os.Setenv("DECKBREW_TEST_PARTNERS", value)
+		if _, err := partners("DECKBREW_TEST_PARTNERS"); err == nil {
+			t.Errorf("Expected %q to return an error", value)
+		}
+	}
+}
@@ -30,6 +30,26 @@ func addCommand(root *cobra.Command, name, desc string, run func() error) {
 	root.AddCommand(command)
 }
 
+// addArgsCommand is like addCommand for commands that take exactly the
+// given number of arguments
+func addArgsCommand(root *cobra.Command, use, desc string, n int, run func([]string) error) {
+	var command = &cobra.Command{
+		Use:   use,
+		Short: desc,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != n {
+				cmd.Usage()
+				log.Fatalf("command-error %s expects %d argument(s)", cmd.Name(), n)
+			}
+			err := run(args)
+			if err != nil {
+				log.Fatalf("command-error %s", err)
+			}
+		},
+	}
+	root.AddCommand(command)
+}
+
 func main() {
 	log.SetFlags(0)
 	opentracing.InitGlobalTracer(&logtrace.Tracer{})
@@ -38,6 +58,24 @@ func main() {
 	addCommand(rootCmd, "migrate", "Migrate the database to the latest scheme", api.MigrateDatabase)
 	addCommand(rootCmd, "serve", "Start and serve the REST API", Serve)
 	addCommand(rootCmd, "sync", "Add new cards to the card database", api.SyncCards)
+
+	var pricesCmd = &cobra.Command{Use: "prices", Short: "Manage card prices"}
+	addArgsCommand(pricesCmd, "import <file>", "Import a CSV or JSON price guide", 1, func(args []string) error {
+		return api.ImportPrices(args[0])
+	})
+	rootCmd.AddCommand(pricesCmd)
+
+	var tcgCmd = &cobra.Command{Use: "tcg", Short: "Manage TCGplayer integration"}
+	var mapCmd = &cobra.Command{Use: "map", Short: "Manage TCGplayer name and set overrides"}
+	addCommand(mapCmd, "list", "List every name and set override", api.ListTCGMappings)
+	addArgsCommand(mapCmd, "add <name|set> <multiverse_id|set_id> <tcgplayer name>", "Add or replace an override", 3, func(args []string) error {
+		return api.AddTCGMapping(args[0], args[1], args[2])
+	})
+	addArgsCommand(mapCmd, "remove <name|set> <multiverse_id|set_id>", "Remove an override", 2, func(args []string) error {
+		return api.RemoveTCGMapping(args[0], args[1])
+	})
+	tcgCmd.AddCommand(mapCmd)
+	rootCmd.AddCommand(tcgCmd)
 	rootCmd.Execute()
 }
 
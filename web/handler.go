@@ -71,7 +71,7 @@ func (web *Web) HandleCard(ctx context.Context, w http.ResponseWriter, r *http.R
 
 	cp := CardPage{Card: cards[0]}
 	for _, e := range cards[0].Editions {
-		if e.MultiverseId == id {
+		if e.HasMultiverseId(id) {
 			cp.Edition = e
 		}
 	}